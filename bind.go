@@ -0,0 +1,175 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"mime"
+	"net/http"
+	"reflect"
+)
+
+// defaultMaxMemory is the default MaxMemory of BindOptions.
+const defaultMaxMemory = 32 << 20
+
+// PathParamFunc returns the value of a path parameter of r by name and
+// true, or false if r has no such parameter.
+type PathParamFunc func(r *http.Request, name string) (string, bool)
+
+// BindOptions define how BindRequest binds request parts to struct fields.
+type BindOptions struct {
+	// StructOptions define how struct fields are treated.
+	StructOptions
+	// PathParam extracts path parameters, i.e. by querying a router. If
+	// nil, fields tagged "path" are not bound.
+	PathParam PathParamFunc
+	// CaseInsensitive, if true, matches query and form keys to field names
+	// ignoring case if no name matches exactly.
+	CaseInsensitive bool
+	// MaxMemory is the maximum number of bytes of a multipart form stored
+	// in memory. If zero, 32 MB is used.
+	MaxMemory int64
+}
+
+// BindRequest binds parts of r to fields of a struct pointed to by out
+// using options opts. Fields are bound from the source named by their tag:
+//
+//	type Request struct {
+//		ID      int    `path:"id"`
+//		Page    int    `query:"page"`
+//		Filter  Filter `query:"filter"`
+//		Name    string `form:"name"`
+//		Trace   string `header:"X-Trace-Id"`
+//		Session string `cookie:"session"`
+//	}
+//
+// Query and form values are decoded as by DecodeValues with fields matched
+// by the "query" and "form" tags respectively, so bracketed keys bind
+// nested structs and maps. Form values are read from the body, which is
+// parsed only if a field is tagged "form". Header, cookie and path values
+// bind top level fields only. Repeated values fill slices in all sources.
+// Top level fields without a tag of a source are not bound from it; fields
+// of nested structs are bound by name if untagged. A field tagged for
+// multiple sources is bound from each, in order of query, form, header,
+// cookie and path.
+//
+// All sources are bound and all errors are returned as a MultiError,
+// suitable for a 400 response. Conversion errors are *ConversionError
+// whose Path is the source and the key, i.e. "query:page" or
+// "header:X-Count". A body that fails to parse as a form returns an error
+// wrapping ErrParse. If r is nil or out is not a non-nil pointer to a
+// struct ErrInvalidParam is returned.
+func BindRequest(r *http.Request, out interface{}, opts BindOptions) error {
+	pv := reflect.ValueOf(out)
+	if r == nil || pv.Kind() != reflect.Ptr || pv.IsNil() || pv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidParam
+	}
+	v := pv.Elem()
+	vo := ValuesOptions{
+		StructOptions:   opts.StructOptions,
+		TaggedOnly:      true,
+		CaseInsensitive: opts.CaseInsensitive,
+		CollectErrors:   true,
+	}
+	ec := &errorCollector{collect: true}
+	fields := TypeInfoOf(v.Type()).fieldList(opts.StructOptions)
+	if r.URL != nil {
+		vo.Tag = "query"
+		if err := vo.DecodeValues(r.URL.Query(), out); err != nil {
+			ec.add(prefixPath("query:", err))
+		}
+	}
+	if hasTag(fields, "form") {
+		if err := parseForm(r, opts.MaxMemory); err != nil {
+			ec.add(ErrParse.WrapCause("cannot parse form", err))
+		} else {
+			vo.Tag = "form"
+			if err := vo.DecodeValues(r.PostForm, out); err != nil {
+				ec.add(prefixPath("form:", err))
+			}
+		}
+	}
+	for _, source := range []string{"header", "cookie", "path"} {
+		for _, field := range fields {
+			tag, ok := field.Tags[source]
+			if !ok || tag.Name == "-" {
+				continue
+			}
+			name := tag.Name
+			if name == "" {
+				name = field.Name
+			}
+			vals := requestValues(r, source, name, opts.PathParam)
+			if len(vals) == 0 {
+				continue
+			}
+			fv, ok := fieldByIndexAlloc(v, field.Index, opts.AllowUnexported)
+			if !ok {
+				continue
+			}
+			if err := vo.decode(fv, nil, vals, source+":"+name); err != nil {
+				ec.add(err)
+			}
+		}
+	}
+	return ec.result()
+}
+
+// prefixPath prefixes paths of conversion errors in err with path and
+// returns err. Other errors are returned unchanged.
+func prefixPath(path string, err error) error {
+	switch e := err.(type) {
+	case *ConversionError:
+		e.Path = path + e.Path
+	case MultiError:
+		for i := range e {
+			e[i] = prefixPath(path, e[i])
+		}
+	}
+	return err
+}
+
+// requestValues returns values of r named name from source.
+func requestValues(r *http.Request, source, name string, pathParam PathParamFunc) (vals []string) {
+	switch source {
+	case "header":
+		return r.Header.Values(name)
+	case "cookie":
+		for _, cookie := range r.Cookies() {
+			if cookie.Name == name {
+				vals = append(vals, cookie.Value)
+			}
+		}
+	case "path":
+		if pathParam == nil {
+			return nil
+		}
+		if val, ok := pathParam(r, name); ok {
+			vals = append(vals, val)
+		}
+	}
+	return
+}
+
+// parseForm parses the body of r as a multipart form if its' content type
+// says so or as an url encoded form otherwise.
+func parseForm(r *http.Request, maxMemory int64) error {
+	if maxMemory == 0 {
+		maxMemory = defaultMaxMemory
+	}
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+		return r.ParseMultipartForm(maxMemory)
+	}
+	return r.ParseForm()
+}
+
+// hasTag returns true if any of fields has a tag keyed by key.
+func hasTag(fields []*FieldInfo, key string) bool {
+	for _, field := range fields {
+		if _, ok := field.Tags[key]; ok {
+			return true
+		}
+	}
+	return false
+}
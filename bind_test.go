@@ -0,0 +1,198 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type BindFilter struct {
+	Name string `query:"name"`
+	Tags []string
+}
+
+type BindRequestTest struct {
+	ID      int        `path:"id"`
+	Page    int        `query:"page"`
+	Filter  BindFilter `query:"filter"`
+	Name    string     `form:"name"`
+	Emails  []string   `form:"email"`
+	Trace   string     `header:"X-Trace-Id"`
+	Accept  []string   `header:"Accept"`
+	Session *string    `cookie:"session"`
+	Lang    string     `query:"lang" cookie:"lang"`
+	Ignored string
+}
+
+// bindPathParam extracts the id path parameter from "/users/{id}".
+func bindPathParam(r *http.Request, name string) (string, bool) {
+	if name != "id" || !strings.HasPrefix(r.URL.Path, "/users/") {
+		return "", false
+	}
+	return strings.TrimPrefix(r.URL.Path, "/users/"), true
+}
+
+func TestBindRequest(t *testing.T) {
+	body := url.Values{"name": {"john"}, "email": {"a@b.c", "d@e.f"}, "Ignored": {"x"}}
+	r := httptest.NewRequest("POST", "/users/42?page=2&filter[name]=x&filter[Tags]=a&lang=en&Ignored=x", strings.NewReader(body.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Trace-Id", "trace")
+	r.Header.Add("Accept", "text/html")
+	r.Header.Add("Accept", "application/json")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	r.AddCookie(&http.Cookie{Name: "lang", Value: "hr"})
+	var test BindRequestTest
+	if err := BindRequest(r, &test, BindOptions{PathParam: bindPathParam}); err != nil {
+		t.Fatal(err)
+	}
+	session := "abc"
+	want := BindRequestTest{
+		ID:      42,
+		Page:    2,
+		Filter:  BindFilter{Name: "x", Tags: []string{"a"}},
+		Name:    "john",
+		Emails:  []string{"a@b.c", "d@e.f"},
+		Trace:   "trace",
+		Accept:  []string{"text/html", "application/json"},
+		Session: &session,
+		Lang:    "hr",
+	}
+	if !reflect.DeepEqual(test, want) {
+		t.Fatalf("BindRequest failed: %#v", test)
+	}
+	if err := BindRequest(r, test, BindOptions{}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("BindRequest failed, expected invalid param error")
+	}
+	if err := BindRequest(nil, &test, BindOptions{}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("BindRequest failed, expected invalid param error")
+	}
+}
+
+func TestBindRequestMultipart(t *testing.T) {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	mw.WriteField("name", "john")
+	mw.WriteField("email", "a@b.c")
+	mw.Close()
+	r := httptest.NewRequest("POST", "/", buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	var test BindRequestTest
+	if err := BindRequest(r, &test, BindOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if test.Name != "john" || !reflect.DeepEqual(test.Emails, []string{"a@b.c"}) {
+		t.Fatalf("BindRequest(multipart) failed: %#v", test)
+	}
+	r = httptest.NewRequest("POST", "/", strings.NewReader("broken"))
+	r.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	if err := BindRequest(r, &test, BindOptions{}); !errors.Is(err, ErrParse) {
+		t.Fatal("BindRequest failed, expected parse error")
+	}
+}
+
+func TestBindRequestErrors(t *testing.T) {
+
+	type Test struct {
+		Page  int    `query:"page"`
+		IDs   []int  `query:"id"`
+		Count int    `header:"X-Count"`
+		Size  uint8  `cookie:"size"`
+		ID    int    `path:"id"`
+		Name  string `query:"name"`
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var test Test
+		if err := BindRequest(r, &test, BindOptions{PathParam: bindPathParam}); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			var me MultiError
+			if !errors.As(err, &me) {
+				t.Fatalf("BindRequest failed, expected MultiError: %v", err)
+			}
+			var paths []string
+			for _, err := range me {
+				var ce *ConversionError
+				if !errors.As(err, &ce) {
+					t.Fatalf("BindRequest failed, expected *ConversionError: %v", err)
+				}
+				paths = append(paths, ce.Path)
+			}
+			want := []string{"query:id[1]", "query:page", "header:X-Count", "cookie:size", "path:id"}
+			if !reflect.DeepEqual(paths, want) {
+				t.Fatalf("BindRequest failed, bad error paths: %v", paths)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	r := httptest.NewRequest("GET", "/users/x?page=p&id=1&id=i&name=ok", nil)
+	r.Header.Set("X-Count", "c")
+	r.AddCookie(&http.Cookie{Name: "size", Value: "300"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("BindRequest failed, expected status 400, got %d", w.Code)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "query:page") || !strings.Contains(body, "'300'") {
+		t.Fatalf("BindRequest failed, bad error message: %s", body)
+	}
+	r = httptest.NewRequest("GET", "/users/1?page=1&id=1&name=ok", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("BindRequest failed, expected status 200, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestPrefixPath(t *testing.T) {
+	if err := prefixPath("query:", ErrParse); err != ErrParse {
+		t.Fatalf("prefixPath failed, error not returned unchanged: %v", err)
+	}
+	me := MultiError{ErrParse, &ConversionError{Path: "page", Cause: ErrParse}}
+	if err := prefixPath("query:", me); err.(MultiError)[0] != ErrParse || me[1].(*ConversionError).Path != "query:page" {
+		t.Fatalf("prefixPath failed: %v", err)
+	}
+}
+
+func FuzzBindRequest(f *testing.F) {
+	f.Add("/users/1?page=2&filter[name]=x&filter[Tags]=a", "name=john&email=a", "trace", "session=abc")
+	f.Add("/users/x?lang=en&lang=hr", "%zz", "", "lang=hr; lang=en")
+	f.Fuzz(func(t *testing.T, target, body, header, cookie string) {
+		u, err := url.ParseRequestURI(target)
+		if err != nil {
+			return
+		}
+		r := &http.Request{
+			Method: "POST",
+			URL:    u,
+			Header: http.Header{
+				"Content-Type": {"application/x-www-form-urlencoded"},
+				"X-Trace-Id":   {header},
+				"Cookie":       {cookie},
+			},
+			Body: http.NoBody,
+		}
+		if body != "" {
+			r.Body = io.NopCloser(strings.NewReader(body))
+		}
+		var test BindRequestTest
+		err = BindRequest(r, &test, BindOptions{PathParam: bindPathParam, CaseInsensitive: true})
+		if err != nil && !errors.Is(err, ErrConvert) && !errors.Is(err, ErrParse) {
+			t.Fatalf("BindRequest failed, unexpected error: %v", err)
+		}
+		if test.Ignored != "" {
+			t.Fatal("BindRequest failed, untagged field bound")
+		}
+	})
+}
@@ -0,0 +1,240 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"fmt"
+	"reflect"
+	"unicode"
+)
+
+// StructBuilder builds struct types at runtime using reflect.StructOf.
+//
+// Builder methods are chainable and the first error that occurs while adding
+// fields is stored and returned by Build.
+type StructBuilder struct {
+	fields []reflect.StructField
+	names  map[string]struct{}
+	err    error
+}
+
+// NewStructBuilder returns a new, empty StructBuilder.
+func NewStructBuilder() *StructBuilder {
+	return &StructBuilder{
+		names: make(map[string]struct{}),
+	}
+}
+
+// AddField adds a field named name of type typ with tag to the struct being
+// built. Name must be a valid exported identifier unique within the struct.
+func (sb *StructBuilder) AddField(name string, typ reflect.Type, tag string) *StructBuilder {
+	return sb.add(reflect.StructField{
+		Name: name,
+		Type: typ,
+		Tag:  reflect.StructTag(tag),
+	})
+}
+
+// Embed adds an anonymous field of type t to the struct being built.
+// T must be a named exported type or a pointer to one. Fields promoted from t
+// are accessible by name via DynamicStruct.
+func (sb *StructBuilder) Embed(t reflect.Type) *StructBuilder {
+	if t == nil {
+		return sb.fail(ErrInvalidParam)
+	}
+	name := t.Name()
+	if t.Kind() == reflect.Ptr {
+		name = t.Elem().Name()
+	}
+	return sb.add(reflect.StructField{
+		Name:      name,
+		Type:      t,
+		Anonymous: true,
+	})
+}
+
+// Merge adds all exported fields of struct type t to the struct being built,
+// preserving their names, types, tags and embedding. It can be used to merge
+// multiple struct types or to widen an existing type with extra fields.
+func (sb *StructBuilder) Merge(t reflect.Type) *StructBuilder {
+	if t == nil {
+		return sb.fail(ErrInvalidParam)
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return sb.fail(ErrInvalidParam)
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		sb.add(reflect.StructField{
+			Name:      field.Name,
+			Type:      field.Type,
+			Tag:       field.Tag,
+			Anonymous: field.Anonymous,
+		})
+	}
+	return sb
+}
+
+// Build returns the built struct type or the first error that occured while
+// adding fields.
+func (sb *StructBuilder) Build() (t reflect.Type, err error) {
+	if sb.err != nil {
+		return nil, sb.err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t, err = nil, ErrInvalidParam.WrapCause("struct build failed", fmt.Errorf("%v", r))
+		}
+	}()
+	return reflect.StructOf(sb.fields), nil
+}
+
+// add adds field to the builder if no error occured so far.
+func (sb *StructBuilder) add(field reflect.StructField) *StructBuilder {
+	if sb.err != nil {
+		return sb
+	}
+	if field.Type == nil || !isExportedIdent(field.Name) {
+		return sb.fail(ErrInvalidParam)
+	}
+	if sb.names == nil {
+		sb.names = make(map[string]struct{})
+	}
+	if _, exists := sb.names[field.Name]; exists {
+		return sb.fail(ErrDuplicateField.WrapArgs(field.Name))
+	}
+	sb.names[field.Name] = struct{}{}
+	sb.fields = append(sb.fields, field)
+	return sb
+}
+
+// fail sets the builder error if not already set.
+func (sb *StructBuilder) fail(err error) *StructBuilder {
+	if sb.err == nil {
+		sb.err = err
+	}
+	return sb
+}
+
+// isExportedIdent returns true if name is a valid exported Go identifier.
+func isExportedIdent(name string) bool {
+	for i, r := range name {
+		if i == 0 && !unicode.IsUpper(r) {
+			return false
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// DynamicStruct is a value-backed accessor to a struct value that provides
+// field access by name. It is primarily intended for use with struct types
+// built by StructBuilder but works with any struct type.
+type DynamicStruct struct {
+	v reflect.Value
+}
+
+// NewDynamicStruct returns a new DynamicStruct backed by a newly allocated
+// zero value of struct type t.
+func NewDynamicStruct(t reflect.Type) (*DynamicStruct, error) {
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, ErrInvalidParam
+	}
+	return &DynamicStruct{reflect.New(t).Elem()}, nil
+}
+
+// DynamicStructOf returns a new DynamicStruct backed by the struct value
+// pointed to by v which must be a non-nil pointer to a struct.
+func DynamicStructOf(v interface{}) (*DynamicStruct, error) {
+	pv := reflect.ValueOf(v)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() || pv.Elem().Kind() != reflect.Struct {
+		return nil, ErrInvalidParam
+	}
+	return &DynamicStruct{pv.Elem()}, nil
+}
+
+// Type returns the struct type of ds or nil if ds is a zero DynamicStruct.
+func (ds *DynamicStruct) Type() reflect.Type {
+	if !ds.v.IsValid() {
+		return nil
+	}
+	return ds.v.Type()
+}
+
+// Value returns the addressable struct value backing ds.
+func (ds *DynamicStruct) Value() reflect.Value { return ds.v }
+
+// Interface returns a pointer to the struct value backing ds or nil if ds is
+// a zero DynamicStruct.
+func (ds *DynamicStruct) Interface() interface{} {
+	if !ds.v.IsValid() {
+		return nil
+	}
+	return ds.v.Addr().Interface()
+}
+
+// Get returns the value of a field by name. Fields promoted from embedded
+// structs are accessible by their name. If the field is not found or is not
+// reachable through a nil embedded pointer an error is returned.
+func (ds *DynamicStruct) Get(name string) (interface{}, error) {
+	field, err := ds.field(name)
+	if err != nil {
+		return nil, err
+	}
+	if !field.CanInterface() {
+		return nil, ErrFieldNotFound.WrapArgs(name)
+	}
+	return field.Interface(), nil
+}
+
+// Set sets the value of a field by name. Value must be assignable or
+// convertible to field type, except numbers which are not converted to
+// strings. A nil value sets the field to its zero value.
+func (ds *DynamicStruct) Set(name string, value interface{}) error {
+	field, err := ds.field(name)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(field.Type()) {
+		field.Set(v)
+		return nil
+	}
+	if convertible(v.Type(), field.Type()) {
+		if cv, ok := convertValue(v, field.Type()); ok {
+			field.Set(cv)
+			return nil
+		}
+	}
+	return ErrConvert.WrapArgs(v.Type(), field.Type())
+}
+
+// field returns a struct field by name.
+func (ds *DynamicStruct) field(name string) (reflect.Value, error) {
+	if !ds.v.IsValid() {
+		return reflect.Value{}, ErrInvalidParam
+	}
+	sf, ok := TypeInfoOf(ds.v.Type()).FieldByName(name)
+	if !ok {
+		return reflect.Value{}, ErrFieldNotFound.WrapArgs(name)
+	}
+	field, ok := fieldByIndex(ds.v, sf.Index, false)
+	if !ok {
+		return reflect.Value{}, ErrFieldNotFound.WrapArgs(name)
+	}
+	return field, nil
+}
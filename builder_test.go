@@ -0,0 +1,117 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type BuilderBase struct {
+	ID   int `json:"id"`
+	Name string
+}
+
+type BuilderExtra struct {
+	Email  string `json:"email"`
+	Active bool
+}
+
+func TestStructBuilder(t *testing.T) {
+	typ, err := NewStructBuilder().
+		AddField("Tenant", reflect.TypeOf(""), `json:"tenant"`).
+		Embed(reflect.TypeOf(&BuilderBase{})).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ.NumField() != 2 {
+		t.Fatal("StructBuilder failed")
+	}
+	if tag := typ.Field(0).Tag.Get("json"); tag != "tenant" {
+		t.Fatal("StructBuilder failed")
+	}
+	ds, err := NewDynamicStruct(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Set("Tenant", "acme"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.Get("ID"); !errors.Is(err, ErrFieldNotFound) {
+		t.Fatal("StructBuilder failed, expected nil embedded pointer error")
+	}
+	if err := ds.Set("BuilderBase", &BuilderBase{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Set("ID", int8(42)); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := ds.Get("ID"); err != nil || v != 42 {
+		t.Fatal("StructBuilder failed")
+	}
+	if v, err := ds.Get("Tenant"); err != nil || v != "acme" {
+		t.Fatal("StructBuilder failed")
+	}
+	if err := ds.Set("Tenant", 42); !errors.Is(err, ErrConvert) {
+		t.Fatal("StructBuilder failed, expected convert error")
+	}
+	if err := ds.Set("Nope", 42); !errors.Is(err, ErrFieldNotFound) {
+		t.Fatal("StructBuilder failed, expected field not found error")
+	}
+}
+
+func TestStructBuilderMerge(t *testing.T) {
+	typ, err := NewStructBuilder().
+		Merge(reflect.TypeOf(BuilderBase{})).
+		Merge(reflect.TypeOf(&BuilderExtra{})).
+		AddField("Score", reflect.TypeOf(0.0), "").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ.NumField() != 5 {
+		t.Fatal("StructBuilder.Merge failed")
+	}
+	if tag := typ.Field(2).Tag.Get("json"); tag != "email" {
+		t.Fatal("StructBuilder.Merge failed")
+	}
+	if _, err := NewStructBuilder().
+		Merge(reflect.TypeOf(BuilderBase{})).
+		AddField("Name", reflect.TypeOf(""), "").
+		Build(); !errors.Is(err, ErrDuplicateField) {
+		t.Fatal("StructBuilder.Merge failed, expected duplicate field error")
+	}
+}
+
+func TestStructBuilderInvalid(t *testing.T) {
+	if _, err := NewStructBuilder().AddField("name", reflect.TypeOf(""), "").Build(); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("StructBuilder failed, expected invalid param error")
+	}
+	if _, err := NewStructBuilder().AddField("Name", nil, "").Build(); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("StructBuilder failed, expected invalid param error")
+	}
+	if _, err := NewStructBuilder().Merge(reflect.TypeOf(0)).Build(); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("StructBuilder failed, expected invalid param error")
+	}
+}
+
+func TestDynamicStructOf(t *testing.T) {
+	base := &BuilderBase{}
+	ds, err := DynamicStructOf(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Set("Name", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if base.Name != "foo" || ds.Interface() != base {
+		t.Fatal("DynamicStructOf failed")
+	}
+	if _, err := DynamicStructOf(BuilderBase{}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("DynamicStructOf failed, expected invalid param error")
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import "reflect"
+
+// CloneInterface returns a deep copy of v. See CloneValue.
+func CloneInterface(v interface{}) interface{} {
+	c := CloneValue(reflect.ValueOf(v))
+	if !c.IsValid() {
+		return nil
+	}
+	return c.Interface()
+}
+
+// CloneValue returns a deep copy of v.
+//
+// Pointers, slices, maps, arrays, exported struct fields and values in
+// interfaces are copied recursively. Unexported struct fields, chans and
+// funcs are copied shallowly. Pointers and maps shared within v are shared
+// within the copy as well, which preserves cycles.
+//
+// If v is invalid or was obtained through an unexported field it is
+// returned as is.
+func CloneValue(v reflect.Value) reflect.Value {
+	if !v.IsValid() || !v.CanInterface() {
+		return v
+	}
+	return cloneValue(v, make(map[visit]reflect.Value))
+}
+
+// cloneValue is the implementation of CloneValue. Cloned maps pointers,
+// maps and slices cloned so far to their copies.
+func cloneValue(v reflect.Value, cloned map[visit]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		key := visitOf(v)
+		if c, ok := cloned[key]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		cloned[key] = c
+		c.Elem().Set(cloneValue(v.Elem(), cloned))
+		return c
+	case reflect.Interface:
+		c := reflect.New(v.Type()).Elem()
+		if !v.IsNil() {
+			c.Set(cloneValue(v.Elem(), cloned))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		key := visitOf(v)
+		if c, ok := cloned[key]; ok && c.Len() == v.Len() {
+			return c
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		cloned[key] = c
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i), cloned))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		key := visitOf(v)
+		if c, ok := cloned[key]; ok {
+			return c
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		cloned[key] = c
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(cloneValue(iter.Key(), cloned), cloneValue(iter.Value(), cloned))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i), cloned))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			c.Field(i).Set(cloneValue(v.Field(i), cloned))
+		}
+		return c
+	}
+	return v
+}
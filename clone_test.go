@@ -0,0 +1,53 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"reflect"
+	"testing"
+)
+
+type CloneNode struct {
+	Name   string
+	Tags   []string
+	Attrs  map[string]interface{}
+	Next   *CloneNode
+	Array  [2]*int
+	hidden *int
+}
+
+func TestCloneValue(t *testing.T) {
+	one := 1
+	node := &CloneNode{
+		Name:   "root",
+		Tags:   []string{"a"},
+		Attrs:  map[string]interface{}{"list": []int{1}},
+		Array:  [2]*int{&one, &one},
+		hidden: &one,
+	}
+	node.Next = node
+	clone := CloneInterface(node).(*CloneNode)
+	if clone == node || clone.Next != clone {
+		t.Fatal("CloneValue failed, cycle not preserved")
+	}
+	if !reflect.DeepEqual(clone, node) {
+		t.Fatal("CloneValue failed, clone not equal")
+	}
+	clone.Tags[0] = "b"
+	clone.Attrs["list"].([]int)[0] = 2
+	*clone.Array[0] = 2
+	if node.Tags[0] != "a" || node.Attrs["list"].([]int)[0] != 1 || one != 1 {
+		t.Fatal("CloneValue failed, copy not deep")
+	}
+	if clone.Array[0] != clone.Array[1] {
+		t.Fatal("CloneValue failed, shared pointer not preserved")
+	}
+	if clone.hidden != node.hidden {
+		t.Fatal("CloneValue failed, unexported field not copied shallowly")
+	}
+	if CloneInterface(nil) != nil || CloneValue(reflect.Value{}).IsValid() {
+		t.Fatal("CloneValue failed, expected invalid value")
+	}
+}
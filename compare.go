@@ -14,7 +14,12 @@ import (
 // CompareInterfaces compares two interfaces for equality between the types
 // contained within them. See CompareValues for details.
 func CompareInterfaces(a, b interface{}) int {
-	return CompareValues(reflect.ValueOf(a), reflect.ValueOf(b))
+	return StructOptions{}.CompareInterfaces(a, b)
+}
+
+// CompareInterfaces is CompareInterfaces using options o.
+func (o StructOptions) CompareInterfaces(a, b interface{}) int {
+	return o.CompareValues(reflect.ValueOf(a), reflect.ValueOf(b))
 }
 
 // CompareValues recursively compares two possibly compound values a and b for
@@ -43,7 +48,8 @@ func CompareInterfaces(a, b interface{}) int {
 // Comparison is done in ascending order after converting keys to strings/bytes.
 //
 // Pointer types are dereferenced do their values before comparison. Untyped
-// pointers are compared by their address numerically.
+// pointers are compared by their address numerically. Pointers, maps and
+// slices reached again through a reference cycle compare equal.
 //
 // Complex numbers are compared as strings.
 //
@@ -51,12 +57,52 @@ func CompareInterfaces(a, b interface{}) int {
 //
 // If an error occurs it is returned with a compare value that should be
 // disregarded.
-//
 func CompareValues(a, b reflect.Value) int {
+	return StructOptions{}.CompareValues(a, b)
+}
+
+// CompareValues is CompareValues using options o. If o.Flatten is true
+// structs are compared by fields with embedded structs flattened and fields
+// unreachable through nil embedded pointers compare as invalid values. If
+// o.AllowUnexported is true unexported fields are compared as well.
+func (o StructOptions) CompareValues(a, b reflect.Value) int {
+	return o.compare(a, b, nil)
+}
+
+// comparison is a pair of references being compared.
+type comparison struct {
+	a, b uintptr
+	typ  reflect.Type
+	// alen and blen are lengths of slices as slices sharing an array differ
+	// by length.
+	alen, blen int
+}
+
+// compare is the implementation of CompareValues. Visited holds pairs of
+// references compared so far and is allocated when first required.
+func (o StructOptions) compare(a, b reflect.Value, visited map[comparison]bool) int {
 	// Compare kinds.
 	if res := compareKind(a.Kind(), b.Kind()); res != 0 {
 		return res
 	}
+	// Stop on reference cycles. A pair compared before either compared
+	// equal or is being compared, so it is considered equal.
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if !a.IsNil() && !b.IsNil() {
+			if visited == nil {
+				visited = make(map[comparison]bool)
+			}
+			key := comparison{a: a.Pointer(), b: b.Pointer(), typ: a.Type()}
+			if a.Kind() == reflect.Slice {
+				key.alen, key.blen = a.Len(), b.Len()
+			}
+			if visited[key] {
+				return 0
+			}
+			visited[key] = true
+		}
+	}
 	// Dereference pointers to values and compare pointer depth.
 	apd, bpd := 0, 0
 	for a.Kind() == reflect.Ptr {
@@ -73,6 +119,10 @@ func CompareValues(a, b reflect.Value) int {
 	if bpd > apd {
 		return -1
 	}
+	// Compare kinds of dereferenced values.
+	if res := compareKind(a.Kind(), b.Kind()); res != 0 {
+		return res
+	}
 	// Compare by Kind.
 	switch a.Kind() {
 	case reflect.Bool:
@@ -134,7 +184,7 @@ func CompareValues(a, b reflect.Value) int {
 		}
 		if a.Len() == b.Len() {
 			for i := 0; i < a.Len(); i++ {
-				if res := CompareValues(a.Index(i), b.Index(i)); res != 0 {
+				if res := o.compare(a.Index(i), b.Index(i), visited); res != 0 {
 					return res
 				}
 			}
@@ -177,7 +227,7 @@ func CompareValues(a, b reflect.Value) int {
 				if res := compareKind(aval.Kind(), bval.Kind()); res != 0 {
 					return res
 				}
-				if res := CompareValues(aval, bval); res != 0 {
+				if res := o.compare(aval, bval, visited); res != 0 {
 					return res
 				}
 			}
@@ -185,35 +235,19 @@ func CompareValues(a, b reflect.Value) int {
 	case reflect.String:
 		return strings.Compare(a.String(), b.String())
 	case reflect.Struct:
-		// Enum public fields.
-		aflds := make([]reflect.StructField, 0, a.NumField())
-		bflds := make([]reflect.StructField, 0, b.NumField())
-		for i := 0; i < a.NumField(); i++ {
-			if !a.Field(i).CanSet() {
-				continue
-			}
-			aflds = append(aflds, a.Type().Field(i))
-		}
-		for i := 0; i < b.NumField(); i++ {
-			if !b.Field(i).CanSet() {
-				continue
-			}
-			bflds = append(bflds, b.Type().Field(i))
+		if o.AllowUnexported {
+			a, b = addressable(a), addressable(b)
 		}
-		// Compare by public field count.
+		// Compare by field count.
+		aflds := TypeInfoOf(a.Type()).sortedList(o)
+		bflds := TypeInfoOf(b.Type()).sortedList(o)
 		if len(aflds) > len(bflds) {
 			return 1
 		}
 		if len(aflds) < len(bflds) {
 			return -1
 		}
-		// Sort the fields and compare by kind and name.
-		sort.Slice(aflds, func(i, j int) bool {
-			return aflds[i].Name < aflds[j].Name
-		})
-		sort.Slice(bflds, func(i, j int) bool {
-			return bflds[i].Name < bflds[j].Name
-		})
+		// Compare fields sorted by name by kind and name.
 		for i := 0; i < len(aflds); i++ {
 			// Compare kind.
 			if res := compareKind(aflds[i].Type.Kind(), bflds[i].Type.Kind()); res != 0 {
@@ -224,12 +258,14 @@ func CompareValues(a, b reflect.Value) int {
 				return res
 			}
 			// Compare field value.
-			if res := CompareValues(a.FieldByName(aflds[i].Name), b.FieldByName(bflds[i].Name)); res != 0 {
+			afld, _ := fieldByIndex(a, aflds[i].Index, o.AllowUnexported)
+			bfld, _ := fieldByIndex(b, bflds[i].Index, o.AllowUnexported)
+			if res := o.compare(afld, bfld, visited); res != 0 {
 				return res
 			}
 		}
 	case reflect.Interface:
-		return CompareValues(reflect.ValueOf(a.Interface()), reflect.ValueOf(b.Interface()))
+		return o.compare(a.Elem(), b.Elem(), visited)
 	case reflect.Ptr, reflect.UnsafePointer:
 		if a.Pointer() == b.Pointer() {
 			return 0
@@ -232,6 +232,21 @@ func TestCompareInterfacesUnsafepointer(t *testing.T) {
 	}
 }
 
+func TestCompareInterfacesReferences(t *testing.T) {
+
+	s, u := []int{1, 2}, []int{1, 3}
+	if CompareInterfaces([][]int{s[:1], s[:2]}, [][]int{u[:1], u[:2]}) != -1 {
+		t.Fatal("TestCompareInterfacesReferences failed.")
+	}
+	a := map[string]interface{}{}
+	a["self"] = a
+	b := map[string]interface{}{}
+	b["self"] = b
+	if CompareInterfaces(a, b) != 0 {
+		t.Fatal("TestCompareInterfacesReferences failed.")
+	}
+}
+
 func BenchmarkCompareInterfaces(b *testing.B) {
 
 	b.StopTimer()
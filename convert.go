@@ -6,23 +6,164 @@ package reflectex
 
 import (
 	"encoding"
+	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
+// ConversionError describes a failed conversion of a string to a value.
+// It responds to errors.Is(err, ErrConvert) and to errors.Is(err, ErrParse)
+// if the input failed to parse. Cause is unwrapped by errors.Is and As.
+type ConversionError struct {
+	// Path is the path to the element that failed to convert relative to
+	// the converted value, i.e. "[2]" or "[key][0]", empty if the converted
+	// value itself failed.
+	Path string
+	// Input is the string that failed to convert.
+	Input string
+	// Target is the type Input failed to convert to.
+	Target reflect.Type
+	// Cause is the underlying error, i.e. a *strconv.NumError, an error
+	// returned by UnmarshalText, ErrParse or ErrUnsupported.
+	Cause error
+}
+
+// Error implements the error interface.
+func (ce *ConversionError) Error() string {
+	msg := ErrConvert.WrapArgs(ce.Input, ce.Target).Error()
+	if ce.Path != "" {
+		msg += fmt.Sprintf(" at '%s'", ce.Path)
+	}
+	if ce.Cause != nil {
+		msg += fmt.Sprintf(" < %v", ce.Cause)
+	}
+	return msg
+}
+
+// Unwrap returns the Cause.
+func (ce *ConversionError) Unwrap() error { return ce.Cause }
+
+// Is returns true if target is ErrConvert or any of its' parents, or if
+// target is ErrParse or any of its' parents and Cause is a strconv error.
+func (ce *ConversionError) Is(target error) bool {
+	if errors.Is(ErrConvert, target) {
+		return true
+	}
+	var ne *strconv.NumError
+	return errors.As(ce.Cause, &ne) && errors.Is(ErrParse, target)
+}
+
+// MultiError is a list of errors in order of occurence.
+type MultiError []error
+
+// Error implements the error interface.
+func (me MultiError) Error() string {
+	a := make([]string, 0, len(me))
+	for _, err := range me {
+		a = append(a, err.Error())
+	}
+	return strings.Join(a, "; ")
+}
+
+// Is returns true if any of the errors is target.
+func (me MultiError) Is(target error) bool {
+	for _, err := range me {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error that matches target and sets target to it.
+func (me MultiError) As(target interface{}) bool {
+	for _, err := range me {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOut returns ErrInvalidParam if out is not a settable value of a kind
+// in range from first to last kind.
+func checkOut(out reflect.Value, first, last reflect.Kind) error {
+	if !out.CanSet() || out.Kind() < first || out.Kind() > last {
+		return ErrInvalidParam
+	}
+	return nil
+}
+
+// conversionError returns err that occured converting in to type t at path
+// as a *ConversionError. If err already is a *ConversionError or a
+// MultiError of them path is prefixed to their paths instead.
+func conversionError(path, in string, t reflect.Type, err error) error {
+	switch e := err.(type) {
+	case *ConversionError:
+		e.Path = path + e.Path
+		return e
+	case MultiError:
+		for i := range e {
+			e[i] = conversionError(path, in, t, e[i])
+		}
+		return e
+	}
+	return &ConversionError{path, in, t, err}
+}
+
+// errorCollector collects errors of elements of a composite value.
+type errorCollector struct {
+	collect bool
+	errs    MultiError
+}
+
+// add adds err and returns true if the conversion should stop.
+func (ec *errorCollector) add(err error) bool {
+	if me, ok := err.(MultiError); ok {
+		ec.errs = append(ec.errs, me...)
+	} else {
+		ec.errs = append(ec.errs, err)
+	}
+	return !ec.collect
+}
+
+// result returns nil if no errors were collected, collected errors as a
+// MultiError if collecting, the first error otherwise.
+func (ec *errorCollector) result() error {
+	if len(ec.errs) == 0 {
+		return nil
+	}
+	if !ec.collect {
+		return ec.errs[0]
+	}
+	return ec.errs
+}
+
+// ConvertOptions define how StringToValue converts strings.
+type ConvertOptions struct {
+	// CollectErrors, if true, makes converters of compound values convert
+	// all elements and return all errors as a MultiError instead of
+	// returning the first error.
+	CollectErrors bool
+}
+
 // StringToInterface converts string in to out which must be a pointer to an
 // allocated memory defining a type compatible to data contained in string
 // according to rules defined in description of StringToValue.
 func StringToInterface(in string, out interface{}) error {
+	return ConvertOptions{}.StringToInterface(in, out)
+}
+
+// StringToInterface is StringToInterface using options o.
+func (o ConvertOptions) StringToInterface(in string, out interface{}) error {
 	if out == nil {
 		return ErrInvalidParam
 	}
-	return stringToInterface(in, out)
-}
-
-func stringToInterface(in string, out interface{}) error {
-	return StringToValue(in, reflect.Indirect(reflect.ValueOf(out)))
+	return o.StringToValue(in, reflect.Indirect(reflect.ValueOf(out)))
 }
 
 // StringToValue intends to set out to a value parsed from in which must be
@@ -47,14 +188,44 @@ func stringToInterface(in string, out interface{}) error {
 //
 // Chans and func are unsupported.
 //
-// If an error occurs it is returned.
+// ValueToString formats values using the same syntax. CompileParser
+// returns a faster equivalent for repeated conversions to the same type.
+//
+// Values implementing TextUnmarshaler, or whose pointers do if addressable,
+// are unmarshaled by UnmarshalText.
+//
+// If out is not valid or not settable ErrInvalidParam is returned, unless it
+// is a non-nil pointer implementing TextUnmarshaler. If an error occurs it
+// is returned as a *ConversionError whose Path points to the element of a
+// compound value that failed to convert.
 func StringToValue(in string, out reflect.Value) error {
-	bum, ok := out.Interface().(encoding.TextUnmarshaler)
-	if ok {
-		if err := bum.UnmarshalText([]byte(in)); err != nil {
-			return err
+	return ConvertOptions{}.StringToValue(in, out)
+}
+
+// StringToValue is StringToValue using options o. If o.CollectErrors is true
+// errors of compound values are returned as a MultiError of all
+// *ConversionError that occured.
+func (o ConvertOptions) StringToValue(in string, out reflect.Value) error {
+	if !out.IsValid() {
+		return ErrInvalidParam
+	}
+	ti := TypeInfoOf(out.Type())
+	switch {
+	case out.Kind() == reflect.Interface:
+		if out.CanInterface() && !out.IsNil() {
+			if _, ok := out.Interface().(encoding.TextUnmarshaler); ok {
+				return unmarshalText(in, out, out.Type())
+			}
 		}
-		return nil
+	case ti.Implements&IfaceTextUnmarshaler != 0:
+		if out.CanInterface() && (out.Kind() != reflect.Ptr || !out.IsNil()) {
+			return unmarshalText(in, out, out.Type())
+		}
+	case ti.PtrImplements&IfaceTextUnmarshaler != 0 && out.CanSet():
+		return unmarshalText(in, out.Addr(), out.Type())
+	}
+	if !out.CanSet() {
+		return ErrInvalidParam
 	}
 	switch out.Kind() {
 	case reflect.Bool:
@@ -74,105 +245,152 @@ func StringToValue(in string, out reflect.Value) error {
 	case reflect.String:
 		return StringToStringValue(in, out)
 	case reflect.Array:
-		return StringToArrayValue(in, out)
+		return o.stringToArrayValue(in, out)
 	case reflect.Slice:
-		return StringToSliceValue(in, out)
+		return o.stringToSliceValue(in, out)
 	case reflect.Map:
-		return StringToMapValue(in, out)
+		return o.stringToMapValue(in, out)
 	case reflect.Struct:
 		return StringToStructValue(in, out)
 	case reflect.Ptr:
-		return StringToPointerValue(in, out)
+		return o.stringToPointerValue(in, out)
 	}
-	return ErrUnsupported
+	return conversionError("", in, out.Type(), ErrUnsupported)
 }
 
 // StringToBoolValue converts a string to a bool.
 func StringToBoolValue(in string, out reflect.Value) error {
+	if err := checkOut(out, reflect.Bool, reflect.Bool); err != nil {
+		return err
+	}
 	b, err := strconv.ParseBool(in)
 	if err != nil {
-		return err
+		return conversionError("", in, out.Type(), err)
 	}
-	out.Set(reflect.ValueOf(b))
+	out.SetBool(b)
 	return nil
 }
 
-// StringToIntValue converts a string to a int of any width.
+// StringToIntValue converts a string to a int of any width. Numbers out
+// of range of the width return an error.
 func StringToIntValue(in string, out reflect.Value) error {
-	n, err := strconv.ParseInt(in, 10, 64)
-	if err != nil {
+	if err := checkOut(out, reflect.Int, reflect.Int64); err != nil {
 		return err
 	}
-	out.Set(reflect.ValueOf(n).Convert(out.Type()))
+	n, err := strconv.ParseInt(in, 10, out.Type().Bits())
+	if err != nil {
+		return conversionError("", in, out.Type(), err)
+	}
+	out.SetInt(n)
 	return nil
 }
 
-// StringToUintValue converts a string to an uint of any width.
+// StringToUintValue converts a string to an uint of any width. Numbers
+// out of range of the width return an error.
 func StringToUintValue(in string, out reflect.Value) error {
-	n, err := strconv.ParseUint(in, 10, 64)
-	if err != nil {
+	if err := checkOut(out, reflect.Uint, reflect.Uintptr); err != nil {
 		return err
 	}
-	out.Set(reflect.ValueOf(n).Convert(out.Type()))
+	n, err := strconv.ParseUint(in, 10, out.Type().Bits())
+	if err != nil {
+		return conversionError("", in, out.Type(), err)
+	}
+	out.SetUint(n)
 	return nil
 }
 
 // StringToFloat32Value converts a string to a float32.
 func StringToFloat32Value(in string, out reflect.Value) error {
+	if err := checkOut(out, reflect.Float32, reflect.Float64); err != nil {
+		return err
+	}
 	n, err := strconv.ParseFloat(in, 32)
 	if err != nil {
-		return err
+		return conversionError("", in, out.Type(), err)
 	}
-	out.Set(reflect.ValueOf(n).Convert(out.Type()))
+	out.SetFloat(n)
 	return nil
 }
 
 // StringToFloat64Value converts a string to a float64.
 func StringToFloat64Value(in string, out reflect.Value) error {
+	if err := checkOut(out, reflect.Float32, reflect.Float64); err != nil {
+		return err
+	}
 	n, err := strconv.ParseFloat(in, 64)
 	if err != nil {
-		return err
+		return conversionError("", in, out.Type(), err)
 	}
-	out.Set(reflect.ValueOf(n).Convert(out.Type()))
+	out.SetFloat(n)
 	return nil
 }
 
 // StringToComplex64Value converts a string to a complex64.
 func StringToComplex64Value(in string, out reflect.Value) error {
+	if err := checkOut(out, reflect.Complex64, reflect.Complex128); err != nil {
+		return err
+	}
 	n, err := strconv.ParseComplex(in, 64)
 	if err != nil {
-		return err
+		return conversionError("", in, out.Type(), err)
 	}
-	out.Set(reflect.ValueOf(n).Convert(out.Type()))
+	out.SetComplex(n)
 	return nil
 }
 
 // StringToComplex128Value converts a string to a complex128.
 func StringToComplex128Value(in string, out reflect.Value) error {
+	if err := checkOut(out, reflect.Complex64, reflect.Complex128); err != nil {
+		return err
+	}
 	n, err := strconv.ParseComplex(in, 128)
 	if err != nil {
-		return err
+		return conversionError("", in, out.Type(), err)
 	}
-	out.Set(reflect.ValueOf(n).Convert(out.Type()))
+	out.SetComplex(n)
 	return nil
 }
 
 // StringToStringValue converts a string to a string.
 func StringToStringValue(in string, out reflect.Value) error {
-	out.Set(reflect.ValueOf(in))
+	if err := checkOut(out, reflect.String, reflect.String); err != nil {
+		return err
+	}
+	out.SetString(in)
 	return nil
 }
 
 // StringToArrayValue converts a string to an array.
 // String is of the form "elem1,elem2,elemN".
 func StringToArrayValue(in string, out reflect.Value) error {
+	if err := checkOut(out, reflect.Array, reflect.Array); err != nil {
+		return err
+	}
+	return ConvertOptions{}.stringToArrayValue(in, out)
+}
+
+// stringToArrayValue is StringToArrayValue using options o.
+func (o ConvertOptions) stringToArrayValue(in string, out reflect.Value) error {
+	return o.parseArray(in, out, o.StringToValue)
+}
+
+// parseArray converts a string to an array, converting elements using
+// parse.
+func (o ConvertOptions) parseArray(in string, out reflect.Value, parse parser) error {
 	v := reflect.Indirect(reflect.New(out.Type()))
 	a := strings.Split(in, ",")
+	ec := &errorCollector{collect: o.CollectErrors}
 	for i, l := 0, out.Len(); i < l && i < len(a); i++ {
-		if err := StringToValue(strings.TrimSpace(a[i]), v.Index(i)); err != nil {
-			return err
+		elem := strings.TrimSpace(a[i])
+		if err := parse(elem, v.Index(i)); err != nil {
+			if ec.add(conversionError(fmt.Sprintf("[%d]", i), elem, v.Type().Elem(), err)) {
+				break
+			}
 		}
 	}
+	if err := ec.result(); err != nil {
+		return err
+	}
 	out.Set(v)
 	return nil
 }
@@ -180,13 +398,32 @@ func StringToArrayValue(in string, out reflect.Value) error {
 // StringToSliceValue converts a string to a slice.
 // String is of the form "elem1,elem2,elemN".
 func StringToSliceValue(in string, out reflect.Value) error {
+	if err := checkOut(out, reflect.Slice, reflect.Slice); err != nil {
+		return err
+	}
+	return ConvertOptions{}.stringToSliceValue(in, out)
+}
+
+// stringToSliceValue is StringToSliceValue using options o.
+func (o ConvertOptions) stringToSliceValue(in string, out reflect.Value) error {
+	return o.parseSlice(in, out, o.StringToValue)
+}
+
+// parseSlice converts a string to a slice, converting elements using parse.
+func (o ConvertOptions) parseSlice(in string, out reflect.Value, parse parser) error {
 	a := strings.Split(in, ",")
 	parsedval := reflect.MakeSlice(reflect.SliceOf(out.Type().Elem()), len(a), len(a))
+	ec := &errorCollector{collect: o.CollectErrors}
 	for i := 0; i < len(a); i++ {
-		if err := StringToValue(a[i], parsedval.Index(i)); err != nil {
-			return err
+		if err := parse(a[i], parsedval.Index(i)); err != nil {
+			if ec.add(conversionError(fmt.Sprintf("[%d]", i), a[i], out.Type().Elem(), err)) {
+				break
+			}
 		}
 	}
+	if err := ec.result(); err != nil {
+		return err
+	}
 	out.Set(parsedval)
 	return nil
 }
@@ -194,40 +431,383 @@ func StringToSliceValue(in string, out reflect.Value) error {
 // StringToMapValue converts a string to a map.
 // String is of the form: "key1=val1,key2=val2,keyN=valN".
 func StringToMapValue(in string, out reflect.Value) error {
+	if err := checkOut(out, reflect.Map, reflect.Map); err != nil {
+		return err
+	}
+	return ConvertOptions{}.stringToMapValue(in, out)
+}
+
+// stringToMapValue is StringToMapValue using options o.
+func (o ConvertOptions) stringToMapValue(in string, out reflect.Value) error {
+	return o.parseMap(in, out, o.StringToValue, o.StringToValue)
+}
+
+// parseMap converts a string to a map, converting keys using parseKey and
+// values using parseElem.
+func (o ConvertOptions) parseMap(in string, out reflect.Value, parseKey, parseElem parser) error {
 	mt := reflect.MapOf(out.Type().Key(), out.Type().Elem())
 	parsedval := reflect.MakeMap(mt)
 	a := strings.Split(in, ",")
+	ec := &errorCollector{collect: o.CollectErrors}
 	for _, s := range a {
 		pair := strings.Split(s, "=")
 		if len(pair) != 2 {
-			return ErrParse
+			if ec.add(conversionError("", s, mt, ErrParse)) {
+				break
+			}
+			continue
 		}
+		path := "[" + pair[0] + "]"
 		key := reflect.Indirect(reflect.New(mt.Key()))
-		if err := StringToValue(pair[0], key); err != nil {
-			return err
+		if err := parseKey(pair[0], key); err != nil {
+			if ec.add(conversionError(path, pair[0], mt.Key(), err)) {
+				break
+			}
+			continue
 		}
 		val := reflect.Indirect(reflect.New(mt.Elem()))
-		if err := StringToValue(pair[1], val); err != nil {
-			return err
+		if err := parseElem(pair[1], val); err != nil {
+			if ec.add(conversionError(path, pair[1], mt.Elem(), err)) {
+				break
+			}
+			continue
 		}
 		parsedval.SetMapIndex(key, val)
 	}
+	if err := ec.result(); err != nil {
+		return err
+	}
 	out.Set(parsedval)
 	return nil
 }
 
 // StringToStructValue converts a string to a struct.
 func StringToStructValue(in string, out reflect.Value) error {
+	if err := checkOut(out, reflect.Struct, reflect.Struct); err != nil {
+		return err
+	}
 	// TODO Implement StringToStruct
-	return ErrNotImplemented.WrapArgs("StringToStructValue")
+	return conversionError("", in, out.Type(), ErrNotImplemented.WrapArgs("StringToStructValue"))
 }
 
 // StringToPointerValue converts a string to a pointer.
 func StringToPointerValue(in string, out reflect.Value) error {
+	if err := checkOut(out, reflect.Ptr, reflect.Ptr); err != nil {
+		return err
+	}
+	return ConvertOptions{}.stringToPointerValue(in, out)
+}
+
+// stringToPointerValue is StringToPointerValue using options o.
+func (o ConvertOptions) stringToPointerValue(in string, out reflect.Value) error {
+	if out.Type().Implements(textUnmarshalerType) {
+		return parseUnmarshalerPointer(in, out)
+	}
+	return parsePointer(in, out, o.StringToValue)
+}
+
+// parsePointer sets out to a pointer to a new value converted from a string
+// using parse.
+func parsePointer(in string, out reflect.Value, parse parser) error {
 	nv := reflect.New(out.Type().Elem())
-	if err := StringToValue(in, reflect.Indirect(nv)); err != nil {
+	if err := parse(in, reflect.Indirect(nv)); err != nil {
 		return err
 	}
 	out.Set(nv)
 	return nil
 }
+
+// parseUnmarshalerPointer sets out, a pointer implementing TextUnmarshaler,
+// to a pointer to a new value unmarshaled from a string.
+func parseUnmarshalerPointer(in string, out reflect.Value) error {
+	nv := reflect.New(out.Type().Elem())
+	if err := unmarshalText(in, nv, out.Type()); err != nil {
+		return err
+	}
+	out.Set(nv)
+	return nil
+}
+
+// unmarshalText unmarshals a string to v which must implement
+// TextUnmarshaler. T is the type reported on error.
+func unmarshalText(in string, v reflect.Value, t reflect.Type) error {
+	if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(in)); err != nil {
+		return conversionError("", in, t, err)
+	}
+	return nil
+}
+
+// InterfaceToString returns v formatted as a string that StringToInterface
+// parses back to an equal value. See ValueToString.
+func InterfaceToString(v interface{}) (string, error) {
+	return ValueToString(reflect.ValueOf(v))
+}
+
+// ValueToString returns v formatted as a string that StringToValue parses
+// back to an equal value, using the syntax described by StringToValue.
+//
+// Values implementing TextMarshaler are formatted by MarshalText. Pointers
+// and interfaces are formatted as values they point to or contain, nil as
+// an empty string. Map entries are sorted by key.
+//
+// Formatting is not escaped so strings containing separators do not parse
+// back to an equal value when they are elements of a compound value. If v
+// is invalid ErrInvalidParam is returned and if v is or contains a value
+// that cannot be formatted or a reference cycle ErrUnsupported is returned.
+func ValueToString(v reflect.Value) (string, error) {
+	if !v.IsValid() {
+		return "", ErrInvalidParam
+	}
+	sb := &strings.Builder{}
+	if err := valueToString(sb, v, make(map[visit]bool)); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// valueToString writes v formatted as a string to sb. Visited holds
+// references being formatted.
+func valueToString(sb *strings.Builder, v reflect.Value, visited map[visit]bool) error {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+	}
+	if v.Type().Implements(textMarshalerType) && v.CanInterface() {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		sb.Write(text)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.Kind() == reflect.Slice && v.Len() == 0 {
+			break
+		}
+		key := visitOf(v)
+		if visited[key] {
+			return ErrUnsupported
+		}
+		visited[key] = true
+		defer delete(visited, key)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		sb.WriteString(v.String())
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		sb.WriteString(scalarString(v))
+	case reflect.Ptr, reflect.Interface:
+		return valueToString(sb, v.Elem(), visited)
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			if err := valueToString(sb, v.Index(i), visited); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return CompareValues(keys[i], keys[j]) < 0
+		})
+		for i, key := range keys {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			if err := valueToString(sb, key, visited); err != nil {
+				return err
+			}
+			sb.WriteByte('=')
+			if err := valueToString(sb, v.MapIndex(key), visited); err != nil {
+				return err
+			}
+		}
+	default:
+		return ErrUnsupported
+	}
+	return nil
+}
+
+// parser converts a string to a value.
+type parser func(in string, out reflect.Value) error
+
+// parserKey is the key of parserCache.
+type parserKey struct {
+	typ  reflect.Type
+	opts ConvertOptions
+}
+
+// parserCache caches parsers compiled by CompileParser.
+var parserCache sync.Map
+
+// CompileParser returns a func that converts a string to a value of type t
+// using zero ConvertOptions. See ConvertOptions.CompileParser.
+func CompileParser(t reflect.Type) (func(string, reflect.Value) error, error) {
+	return ConvertOptions{}.CompileParser(t)
+}
+
+// CompileParser returns a func that converts a string to a value of type t
+// as StringToValue does using options o, with the TextUnmarshaler check,
+// the dispatch on kind and the converters of element types resolved once.
+// Compiled funcs are cached per type and options and are safe for
+// concurrent use.
+//
+// The compiled func returns ErrInvalidParam if out is not a value of type
+// t. CompileParser returns ErrInvalidParam if t is nil and ErrUnsupported
+// if t is or contains a type that StringToValue cannot convert to.
+func (o ConvertOptions) CompileParser(t reflect.Type) (func(string, reflect.Value) error, error) {
+	if t == nil {
+		return nil, ErrInvalidParam
+	}
+	key := parserKey{t, o}
+	if cached, ok := parserCache.Load(key); ok {
+		return cached.(parser), nil
+	}
+	parse, err := o.compileParser(t, make(map[reflect.Type]*parser))
+	if err != nil {
+		return nil, err
+	}
+	var checked parser = func(in string, out reflect.Value) error {
+		if !out.IsValid() || out.Type() != t {
+			return ErrInvalidParam
+		}
+		return parse(in, out)
+	}
+	cached, _ := parserCache.LoadOrStore(key, checked)
+	return cached.(parser), nil
+}
+
+// compileParser returns a parser of values of type t. Compiling holds
+// parsers of types being compiled so recursive types can refer to them.
+func (o ConvertOptions) compileParser(t reflect.Type, compiling map[reflect.Type]*parser) (parser, error) {
+	if p, ok := compiling[t]; ok {
+		return func(in string, out reflect.Value) error {
+			return (*p)(in, out)
+		}, nil
+	}
+	p := new(parser)
+	compiling[t] = p
+	if ti := TypeInfoOf(t); t.Kind() != reflect.Interface &&
+		(ti.Implements|ti.PtrImplements)&IfaceTextUnmarshaler != 0 {
+		*p = compileUnmarshaler(ti)
+		return *p, nil
+	}
+	parse, err := o.compileKind(t, compiling)
+	if err != nil {
+		return nil, err
+	}
+	*p = parse
+	return parse, nil
+}
+
+// compileUnmarshaler returns a parser of type ti.Type that implements
+// TextUnmarshaler or whose pointer does.
+func compileUnmarshaler(ti *TypeInfo) parser {
+	t := ti.Type
+	if ti.Implements&IfaceTextUnmarshaler == 0 {
+		return func(in string, out reflect.Value) error {
+			if !out.CanSet() {
+				return ErrInvalidParam
+			}
+			return unmarshalText(in, out.Addr(), t)
+		}
+	}
+	if t.Kind() != reflect.Ptr {
+		return func(in string, out reflect.Value) error {
+			if !out.CanInterface() {
+				return ErrInvalidParam
+			}
+			return unmarshalText(in, out, t)
+		}
+	}
+	return func(in string, out reflect.Value) error {
+		if out.CanInterface() && !out.IsNil() {
+			return unmarshalText(in, out, t)
+		}
+		if !out.CanSet() {
+			return ErrInvalidParam
+		}
+		return parseUnmarshalerPointer(in, out)
+	}
+}
+
+// compileKind returns a parser of type t by kind of t.
+func (o ConvertOptions) compileKind(t reflect.Type, compiling map[reflect.Type]*parser) (parser, error) {
+	switch t.Kind() {
+	case reflect.Bool:
+		return StringToBoolValue, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return StringToIntValue, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return StringToUintValue, nil
+	case reflect.Float32:
+		return StringToFloat32Value, nil
+	case reflect.Float64:
+		return StringToFloat64Value, nil
+	case reflect.Complex64:
+		return StringToComplex64Value, nil
+	case reflect.Complex128:
+		return StringToComplex128Value, nil
+	case reflect.String:
+		return StringToStringValue, nil
+	case reflect.Interface:
+		return func(in string, out reflect.Value) error {
+			return o.StringToValue(in, out)
+		}, nil
+	case reflect.Array:
+		elem, err := o.compileParser(t.Elem(), compiling)
+		if err != nil {
+			return nil, err
+		}
+		return func(in string, out reflect.Value) error {
+			if !out.CanSet() {
+				return ErrInvalidParam
+			}
+			return o.parseArray(in, out, elem)
+		}, nil
+	case reflect.Slice:
+		elem, err := o.compileParser(t.Elem(), compiling)
+		if err != nil {
+			return nil, err
+		}
+		return func(in string, out reflect.Value) error {
+			if !out.CanSet() {
+				return ErrInvalidParam
+			}
+			return o.parseSlice(in, out, elem)
+		}, nil
+	case reflect.Map:
+		key, err := o.compileParser(t.Key(), compiling)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := o.compileParser(t.Elem(), compiling)
+		if err != nil {
+			return nil, err
+		}
+		return func(in string, out reflect.Value) error {
+			if !out.CanSet() {
+				return ErrInvalidParam
+			}
+			return o.parseMap(in, out, key, elem)
+		}, nil
+	case reflect.Ptr:
+		elem, err := o.compileParser(t.Elem(), compiling)
+		if err != nil {
+			return nil, err
+		}
+		return func(in string, out reflect.Value) error {
+			if !out.CanSet() {
+				return ErrInvalidParam
+			}
+			return parsePointer(in, out, elem)
+		}, nil
+	}
+	return nil, ErrUnsupported.Wrap(t.String())
+}
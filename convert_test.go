@@ -6,7 +6,9 @@ package reflectex
 
 import (
 	"bytes"
+	"errors"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -39,6 +41,7 @@ func TestStringToValueBool(t *testing.T) {
 }
 
 func BenchmarkStringToValueBool(b *testing.B) {
+	b.ReportAllocs()
 	val := false
 	in := "true"
 	out := reflect.Indirect(reflect.ValueOf(&val))
@@ -48,6 +51,10 @@ func BenchmarkStringToValueBool(b *testing.B) {
 	}
 }
 
+func BenchmarkCompileParserBool(b *testing.B) {
+	benchmarkCompiledParser(b, "true", new(bool))
+}
+
 func TestStringToValueInt(t *testing.T) {
 	val := 0
 	in := "-42"
@@ -61,6 +68,7 @@ func TestStringToValueInt(t *testing.T) {
 }
 
 func BenchmarkStringToValueInt(b *testing.B) {
+	b.ReportAllocs()
 	val := 0
 	in := "-42"
 	out := reflect.Indirect(reflect.ValueOf(&val))
@@ -70,6 +78,10 @@ func BenchmarkStringToValueInt(b *testing.B) {
 	}
 }
 
+func BenchmarkCompileParserInt(b *testing.B) {
+	benchmarkCompiledParser(b, "-42", new(int))
+}
+
 func TestStringToValueUint(t *testing.T) {
 	val := 0
 	in := "1337"
@@ -83,6 +95,7 @@ func TestStringToValueUint(t *testing.T) {
 }
 
 func BenchmarkStringToValueUint(b *testing.B) {
+	b.ReportAllocs()
 	val := 0
 	in := "1337"
 	out := reflect.Indirect(reflect.ValueOf(&val))
@@ -92,6 +105,10 @@ func BenchmarkStringToValueUint(b *testing.B) {
 	}
 }
 
+func BenchmarkCompileParserUint(b *testing.B) {
+	benchmarkCompiledParser(b, "1337", new(int))
+}
+
 func TestStringToValueFloat32(t *testing.T) {
 	val := float32(0.0)
 	in := "3.14"
@@ -104,6 +121,7 @@ func TestStringToValueFloat32(t *testing.T) {
 	}
 }
 func BenchmarkStringToValueFloat32(b *testing.B) {
+	b.ReportAllocs()
 	val := float32(0.0)
 	in := "3.14"
 	out := reflect.Indirect(reflect.ValueOf(&val))
@@ -113,6 +131,10 @@ func BenchmarkStringToValueFloat32(b *testing.B) {
 	}
 }
 
+func BenchmarkCompileParserFloat32(b *testing.B) {
+	benchmarkCompiledParser(b, "3.14", new(float32))
+}
+
 func TestStringToValueFloat64(t *testing.T) {
 	val := float64(0.0)
 	in := "3.14"
@@ -126,6 +148,7 @@ func TestStringToValueFloat64(t *testing.T) {
 }
 
 func BenchmarkStringToValueFloat64(b *testing.B) {
+	b.ReportAllocs()
 	val := float64(0.0)
 	in := "3.14"
 	out := reflect.Indirect(reflect.ValueOf(&val))
@@ -135,6 +158,10 @@ func BenchmarkStringToValueFloat64(b *testing.B) {
 	}
 }
 
+func BenchmarkCompileParserFloat64(b *testing.B) {
+	benchmarkCompiledParser(b, "3.14", new(float64))
+}
+
 func TestStringToValueComplex64(t *testing.T) {
 	val := complex64(complex(0, 0))
 	in := "3.14+10i"
@@ -148,6 +175,7 @@ func TestStringToValueComplex64(t *testing.T) {
 }
 
 func BenchmarkStringToValueComplex64(b *testing.B) {
+	b.ReportAllocs()
 	val := complex64(complex(0, 0))
 	in := "3.14+10i"
 	out := reflect.Indirect(reflect.ValueOf(&val))
@@ -157,6 +185,10 @@ func BenchmarkStringToValueComplex64(b *testing.B) {
 	}
 }
 
+func BenchmarkCompileParserComplex64(b *testing.B) {
+	benchmarkCompiledParser(b, "3.14+10i", new(complex64))
+}
+
 func TestStringToValueComplex128(t *testing.T) {
 	val := complex128(complex(0, 0))
 	in := "3.14+10i"
@@ -170,6 +202,7 @@ func TestStringToValueComplex128(t *testing.T) {
 }
 
 func BenchmarkStringToValueComplex128(b *testing.B) {
+	b.ReportAllocs()
 	val := complex128(complex(0, 0))
 	in := "3.14+10i"
 	out := reflect.Indirect(reflect.ValueOf(&val))
@@ -179,6 +212,10 @@ func BenchmarkStringToValueComplex128(b *testing.B) {
 	}
 }
 
+func BenchmarkCompileParserComplex128(b *testing.B) {
+	benchmarkCompiledParser(b, "3.14+10i", new(complex128))
+}
+
 func TestStringToValueString(t *testing.T) {
 	val := string("")
 	in := "foobar"
@@ -192,6 +229,7 @@ func TestStringToValueString(t *testing.T) {
 }
 
 func BenchmarkStringToValueString(b *testing.B) {
+	b.ReportAllocs()
 	val := string("")
 	in := "foobar"
 	out := reflect.Indirect(reflect.ValueOf(&val))
@@ -201,6 +239,10 @@ func BenchmarkStringToValueString(b *testing.B) {
 	}
 }
 
+func BenchmarkCompileParserString(b *testing.B) {
+	benchmarkCompiledParser(b, "foobar", new(string))
+}
+
 func TestStringToValueArray(t *testing.T) {
 	val := [3]int{0, 0, 0}
 	in := "1,2,3"
@@ -214,6 +256,7 @@ func TestStringToValueArray(t *testing.T) {
 }
 
 func BenchmarkStringToValueArray(b *testing.B) {
+	b.ReportAllocs()
 	val := [3]int{0, 0, 0}
 	in := "1,2,3"
 	out := reflect.Indirect(reflect.ValueOf(&val))
@@ -223,6 +266,10 @@ func BenchmarkStringToValueArray(b *testing.B) {
 	}
 }
 
+func BenchmarkCompileParserArray(b *testing.B) {
+	benchmarkCompiledParser(b, "1,2,3", &[3]int{})
+}
+
 func TestStringToValueSlice(t *testing.T) {
 	val := []byte{}
 	in := "1,2,3"
@@ -236,6 +283,7 @@ func TestStringToValueSlice(t *testing.T) {
 }
 
 func BenchmarkStringToValueSlice(b *testing.B) {
+	b.ReportAllocs()
 	val := []int{0, 0, 0}
 	in := "1,2,3"
 	out := reflect.Indirect(reflect.ValueOf(&val))
@@ -245,6 +293,10 @@ func BenchmarkStringToValueSlice(b *testing.B) {
 	}
 }
 
+func BenchmarkCompileParserSlice(b *testing.B) {
+	benchmarkCompiledParser(b, "1,2,3", &[]int{})
+}
+
 func TestStringToValueMap(t *testing.T) {
 	val := map[string]int{}
 	in := "1=1,2=2,3=3"
@@ -265,6 +317,7 @@ func TestStringToValueMap(t *testing.T) {
 }
 
 func BenchmarkStringToValueMap(b *testing.B) {
+	b.ReportAllocs()
 	val := map[string]int{}
 	in := "1=1,2=2,3=3"
 	out := reflect.Indirect(reflect.ValueOf(&val))
@@ -273,6 +326,10 @@ func BenchmarkStringToValueMap(b *testing.B) {
 	}
 }
 
+func BenchmarkCompileParserMap(b *testing.B) {
+	benchmarkCompiledParser(b, "1=1,2=2,3=3", &map[string]int{})
+}
+
 func TestStringToPointerValue(t *testing.T) {
 	in := "69"
 	var val *int
@@ -286,6 +343,7 @@ func TestStringToPointerValue(t *testing.T) {
 }
 
 func BenchmarkStringToPointerValue(b *testing.B) {
+	b.ReportAllocs()
 	in := "69"
 	var val *int
 	out := reflect.Indirect(reflect.ValueOf(&val))
@@ -295,6 +353,10 @@ func BenchmarkStringToPointerValue(b *testing.B) {
 	}
 }
 
+func BenchmarkCompileParserPointer(b *testing.B) {
+	benchmarkCompiledParser(b, "69", new(*int))
+}
+
 func TestStringToDeepPointerValue(t *testing.T) {
 	in := "69"
 	var val ***int
@@ -308,6 +370,7 @@ func TestStringToDeepPointerValue(t *testing.T) {
 }
 
 func BenchmarkStringToDeepPointerValue(b *testing.B) {
+	b.ReportAllocs()
 	in := "69"
 	var val ***int
 	out := reflect.Indirect(reflect.ValueOf(&val))
@@ -317,6 +380,10 @@ func BenchmarkStringToDeepPointerValue(b *testing.B) {
 	}
 }
 
+func BenchmarkCompileParserDeepPointer(b *testing.B) {
+	benchmarkCompiledParser(b, "69", new(***int))
+}
+
 func TestStringToInterface(t *testing.T) {
 	s := ""
 	if err := StringToInterface("string", &s); err != nil {
@@ -345,3 +412,375 @@ func TestStringToInterface(t *testing.T) {
 		t.Fatal("map", err)
 	}
 }
+
+// benchmarkCompiledParser benchmarks converting in to the value ptr points
+// to using a compiled parser.
+func benchmarkCompiledParser(b *testing.B, in string, ptr interface{}) {
+	out := reflect.ValueOf(ptr).Elem()
+	parse, err := CompileParser(out.Type())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parse(in, out)
+	}
+}
+
+// CompileParserMap is a recursive map type.
+type CompileParserMap map[string]CompileParserMap
+
+func TestCompileParser(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		in  string
+		ptr interface{}
+	}{
+		{"true", new(bool)},
+		{"-42", new(int8)},
+		{"3.14", new(float32)},
+		{"3.14+10i", new(complex128)},
+		{"foo", new(string)},
+		{"1, 2,3", &[2]int{}},
+		{"1,2,3", &[]uint{}},
+		{"a=1,b=2", &map[string]*int{}},
+		{"69", new(***int)},
+		{now.Format(time.RFC3339Nano), new(*time.Time)},
+	}
+	for i, test := range tests {
+		out := reflect.ValueOf(test.ptr).Elem()
+		parse, err := CompileParser(out.Type())
+		if err != nil {
+			t.Fatalf("CompileParser failed at %d: %v", i, err)
+		}
+		if err := parse(test.in, out); err != nil {
+			t.Fatalf("CompileParser failed at %d: %v", i, err)
+		}
+		want := reflect.New(out.Type()).Elem()
+		if err := StringToValue(test.in, want); err != nil {
+			t.Fatalf("StringToValue failed at %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(out.Interface(), want.Interface()) {
+			t.Fatalf("CompileParser failed at %d: want %v, got %v", i, want, out)
+		}
+	}
+	parse, err := CompileParser(reflect.TypeOf(CompileParserMap{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parse("a=b", reflect.ValueOf(&CompileParserMap{}).Elem()); !errors.Is(err, ErrParse) {
+		t.Fatal("CompileParser failed, expected parse error on recursive type")
+	}
+	if parse, err = CompileParser(reflect.TypeOf(0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := parse("x", reflect.ValueOf(new(int)).Elem()); !errors.Is(err, ErrParse) {
+		t.Fatal("CompileParser failed, expected parse error")
+	}
+	if err := parse("1", reflect.ValueOf(new(int8)).Elem()); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("CompileParser failed, expected invalid param error on type mismatch")
+	}
+	if err := parse("1", reflect.ValueOf(0)); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("CompileParser failed, expected invalid param error on unsettable value")
+	}
+	if _, err := CompileParser(nil); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("CompileParser failed, expected invalid param error")
+	}
+	if _, err := CompileParser(reflect.TypeOf(map[string][]chan int{})); !errors.Is(err, ErrUnsupported) {
+		t.Fatal("CompileParser failed, expected unsupported error")
+	}
+}
+
+func TestConvertOptionsCompileParser(t *testing.T) {
+	var val []int
+	out := reflect.ValueOf(&val).Elem()
+	parse, err := ConvertOptions{CollectErrors: true}.CompileParser(out.Type())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = parse("a,1,b", out)
+	var me MultiError
+	if !errors.As(err, &me) || len(me) != 2 {
+		t.Fatalf("CompileParser(CollectErrors) failed: %v", err)
+	}
+	var ce *ConversionError
+	if !errors.As(me[1], &ce) || ce.Path != "[2]" {
+		t.Fatalf("CompileParser(CollectErrors) failed, bad path: %v", me[1])
+	}
+}
+
+func TestStringToValueAllocs(t *testing.T) {
+	tests := []struct {
+		in  string
+		ptr interface{}
+	}{
+		{"true", new(bool)},
+		{"-42", new(int16)},
+		{"1337", new(uintptr)},
+		{"3.14", new(float32)},
+		{"3.14", new(float64)},
+		{"3.14+10i", new(complex64)},
+		{"3.14+10i", new(complex128)},
+		{"foobar", new(string)},
+	}
+	for i, test := range tests {
+		out := reflect.ValueOf(test.ptr).Elem()
+		parse, err := CompileParser(out.Type())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := testing.AllocsPerRun(100, func() { StringToValue(test.in, out) }); n != 0 {
+			t.Fatalf("StringToValue allocated %v times at %d", n, i)
+		}
+		if n := testing.AllocsPerRun(100, func() { parse(test.in, out) }); n != 0 {
+			t.Fatalf("CompileParser allocated %v times at %d", n, i)
+		}
+	}
+}
+
+func TestStringToValueOverflow(t *testing.T) {
+	for _, ptr := range []interface{}{new(int8), new(uint8), new(int32)} {
+		out := reflect.ValueOf(ptr).Elem()
+		if err := StringToValue("4294967296", out); !errors.Is(err, ErrParse) {
+			t.Fatalf("StringToValue(%s) failed, expected parse error", out.Type())
+		}
+	}
+	var val uint16
+	if err := StringToInterface("65535", &val); err != nil || val != 65535 {
+		t.Fatal("StringToValue(uint16) failed")
+	}
+}
+
+func TestStringToValueAddressableUnmarshaler(t *testing.T) {
+	now := time.Now().UTC()
+	var val struct {
+		Times []time.Time
+	}
+	in := now.Format(time.RFC3339Nano)
+	if err := StringToInterface(in, &val.Times); err != nil {
+		t.Fatal(err)
+	}
+	if len(val.Times) != 1 || !val.Times[0].Equal(now) {
+		t.Fatal("StringToValue(addressable TextUnmarshaler) failed")
+	}
+	if err := StringToValue(in, reflect.ValueOf(now)); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("StringToValue failed, expected invalid param error")
+	}
+}
+
+func TestConversionError(t *testing.T) {
+	var out map[string][]int
+	err := StringToInterface("a=1,b=x", &out)
+	var ce *ConversionError
+	if !errors.As(err, &ce) {
+		t.Fatalf("StringToValue failed, expected *ConversionError, got %v", err)
+	}
+	if ce.Path != "[b][0]" || ce.Input != "x" || ce.Target != reflect.TypeOf(0) {
+		t.Fatalf("StringToValue failed, unexpected error %#v", ce)
+	}
+	if !errors.Is(err, ErrConvert) || !errors.Is(err, ErrParse) || !errors.Is(err, ErrReflectEx) {
+		t.Fatal("StringToValue failed, error does not match sentinels")
+	}
+	var ne *strconv.NumError
+	if !errors.As(err, &ne) {
+		t.Fatal("StringToValue failed, cause is not a *strconv.NumError")
+	}
+	if err := StringToInterface("a", &out); !errors.Is(err, ErrParse) {
+		t.Fatalf("StringToValue failed, expected parse error, got %v", err)
+	}
+	var ch chan int
+	if err := StringToInterface("1", &ch); !errors.Is(err, ErrUnsupported) || errors.Is(err, ErrParse) {
+		t.Fatalf("StringToValue failed, expected unsupported error, got %v", err)
+	}
+}
+
+func TestConvertOptionsCollectErrors(t *testing.T) {
+	var out []int
+	err := StringToInterface("x,1,y", &out)
+	if _, ok := err.(*ConversionError); !ok {
+		t.Fatalf("StringToValue failed, expected *ConversionError, got %v", err)
+	}
+	err = ConvertOptions{CollectErrors: true}.StringToInterface("x,1,y", &out)
+	me, ok := err.(MultiError)
+	if !ok || len(me) != 2 {
+		t.Fatalf("StringToValue(CollectErrors) failed, got %v", err)
+	}
+	if me[0].(*ConversionError).Path != "[0]" || me[1].(*ConversionError).Path != "[2]" {
+		t.Fatalf("StringToValue(CollectErrors) failed, got %v", err)
+	}
+	if !errors.Is(err, ErrParse) {
+		t.Fatal("StringToValue(CollectErrors) failed, error does not match sentinels")
+	}
+	if out != nil {
+		t.Fatal("StringToValue(CollectErrors) failed, out modified on error")
+	}
+}
+
+func TestValueToString(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{true, "true"},
+		{int8(-8), "-8"},
+		{float32(0.1), "0.1"},
+		{complex(1, -2), "(1-2i)"},
+		{[]string{"a", "b"}, "a,b"},
+		{[2]int{1, 2}, "1,2"},
+		{map[string]int{"b": 2, "a": 1}, "a=1,b=2"},
+		{func() *int { v := 42; return &v }(), "42"},
+		{(*int)(nil), ""},
+		{time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), "2020-01-02T03:04:05Z"},
+	}
+	for _, test := range tests {
+		s, err := InterfaceToString(test.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != test.want {
+			t.Fatalf("InterfaceToString(%#v) failed: want '%s', got '%s'", test.in, test.want, s)
+		}
+	}
+	if _, err := InterfaceToString(nil); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("InterfaceToString failed, expected invalid param error")
+	}
+	if _, err := InterfaceToString([]chan int{nil, make(chan int)}); !errors.Is(err, ErrUnsupported) {
+		t.Fatal("InterfaceToString failed, expected unsupported error")
+	}
+	cyclic := []interface{}{nil}
+	cyclic[0] = cyclic
+	if _, err := InterfaceToString(cyclic); !errors.Is(err, ErrUnsupported) {
+		t.Fatal("InterfaceToString failed, expected unsupported error on cycle")
+	}
+}
+
+// FuzzBool is a named bool type used in fuzz tests.
+type FuzzBool bool
+
+// FuzzString is a named string type used in fuzz tests.
+type FuzzString string
+
+// fuzzRoundTrip parses s to a new value of each of types and checks that
+// a parsed value formats to a string that parses again and formats to the
+// same string.
+func fuzzRoundTrip(t *testing.T, s string, types ...reflect.Type) {
+	for _, typ := range types {
+		parse, err := CompileParser(typ)
+		if err != nil {
+			t.Fatalf("%s: compiling parser failed: %v", typ, err)
+		}
+		compiled := reflect.New(typ).Elem()
+		compiledErr := parse(s, compiled)
+		v := reflect.New(typ).Elem()
+		if err := StringToValue(s, v); err != nil {
+			var ce *ConversionError
+			if !errors.As(err, &ce) {
+				t.Fatalf("%s: parsing '%s' returned %v, expected *ConversionError", typ, s, err)
+			}
+			if compiledErr == nil || compiledErr.Error() != err.Error() {
+				t.Fatalf("%s: compiled parser returned %v for '%s', expected %v", typ, compiledErr, s, err)
+			}
+			continue
+		}
+		first, err := ValueToString(v)
+		if err != nil {
+			t.Fatalf("%s: formatting value parsed from '%s' failed: %v", typ, s, err)
+		}
+		if compiledErr != nil {
+			t.Fatalf("%s: compiled parser failed parsing '%s': %v", typ, s, compiledErr)
+		}
+		if c, _ := ValueToString(compiled); c != first {
+			t.Fatalf("%s: compiled parser parsed '%s' as '%s', expected '%s'", typ, s, c, first)
+		}
+		v = reflect.New(typ).Elem()
+		if err := StringToValue(first, v); err != nil {
+			t.Fatalf("%s: parsing '%s' formatted from '%s' failed: %v", typ, first, s, err)
+		}
+		second, err := ValueToString(v)
+		if err != nil {
+			t.Fatalf("%s: formatting value parsed from '%s' failed: %v", typ, first, err)
+		}
+		if first != second {
+			t.Fatalf("%s: round trip of '%s' not stable: '%s' != '%s'", typ, s, first, second)
+		}
+	}
+}
+
+func FuzzStringToBool(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		fuzzRoundTrip(t, s, reflect.TypeOf(false), reflect.TypeOf(FuzzBool(false)))
+	})
+}
+
+func FuzzStringToInt(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		fuzzRoundTrip(t, s, reflect.TypeOf(int(0)), reflect.TypeOf(int8(0)), reflect.TypeOf(int16(0)),
+			reflect.TypeOf(int32(0)), reflect.TypeOf(int64(0)), reflect.TypeOf(FuzzNamed(0)))
+	})
+}
+
+func FuzzStringToUint(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		fuzzRoundTrip(t, s, reflect.TypeOf(uint(0)), reflect.TypeOf(uint8(0)), reflect.TypeOf(uint16(0)),
+			reflect.TypeOf(uint32(0)), reflect.TypeOf(uint64(0)), reflect.TypeOf(uintptr(0)))
+	})
+}
+
+func FuzzStringToFloat32(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		fuzzRoundTrip(t, s, reflect.TypeOf(float32(0)))
+	})
+}
+
+func FuzzStringToFloat64(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		fuzzRoundTrip(t, s, reflect.TypeOf(float64(0)))
+	})
+}
+
+func FuzzStringToComplex64(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		fuzzRoundTrip(t, s, reflect.TypeOf(complex64(0)))
+	})
+}
+
+func FuzzStringToComplex128(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		fuzzRoundTrip(t, s, reflect.TypeOf(complex128(0)))
+	})
+}
+
+func FuzzStringToString(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		fuzzRoundTrip(t, s, reflect.TypeOf(""), reflect.TypeOf(FuzzString("")))
+	})
+}
+
+func FuzzStringToArray(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		fuzzRoundTrip(t, s, reflect.TypeOf([3]int{}), reflect.TypeOf([2]string{}),
+			reflect.TypeOf([2]bool{}), reflect.TypeOf([0]int{}))
+	})
+}
+
+func FuzzStringToSlice(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		fuzzRoundTrip(t, s, reflect.TypeOf([]int{}), reflect.TypeOf([]string{}),
+			reflect.TypeOf([]byte{}), reflect.TypeOf([]float64{}), reflect.TypeOf([][]int{}))
+	})
+}
+
+func FuzzStringToMap(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		fuzzRoundTrip(t, s, reflect.TypeOf(map[string]int{}), reflect.TypeOf(map[int]string{}),
+			reflect.TypeOf(map[string]string{}), reflect.TypeOf(map[string][]int{}))
+	})
+}
+
+func FuzzStringToPointer(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		fuzzRoundTrip(t, s, reflect.TypeOf((*int)(nil)), reflect.TypeOf((**string)(nil)),
+			reflect.TypeOf((*[]int)(nil)), reflect.TypeOf(map[string]*int{}))
+	})
+}
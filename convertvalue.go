@@ -0,0 +1,372 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Convert returns src converted to a value of type t using zero
+// ConvertOptions. See ConvertOptions.ConvertValue.
+func Convert(src interface{}, t reflect.Type) (reflect.Value, error) {
+	return ConvertOptions{}.ConvertValue(reflect.ValueOf(src), t)
+}
+
+// ConvertValue returns src converted to a value of type t using zero
+// ConvertOptions. See ConvertOptions.ConvertValue.
+func ConvertValue(src reflect.Value, t reflect.Type) (reflect.Value, error) {
+	return ConvertOptions{}.ConvertValue(src, t)
+}
+
+// Convert returns src converted to a value of type t using options o.
+// See ConvertValue.
+func (o ConvertOptions) Convert(src interface{}, t reflect.Type) (reflect.Value, error) {
+	return o.ConvertValue(reflect.ValueOf(src), t)
+}
+
+// ConvertValue returns src converted to a new addressable value of type t
+// using options o. Conversion rules are, in order:
+//
+// Invalid values and nil pointers, interfaces, slices and maps convert to
+// zero values. Interfaces and pointers are dereferenced and values of type
+// t are copied. Pointer types are allocated and pointed to converted values.
+//
+// Strings are parsed by StringToValue, except to slices of bytes or runes
+// which are converted as by Go. Values convert to strings as formatted by
+// ValueToString, except slices of bytes or runes which are converted as by
+// Go.
+//
+// Numbers convert to numbers of any kind and width if the value is
+// representable in the target type, otherwise the error cause is
+// strconv.ErrRange. Floats convert to integers only if they have no
+// fractional part and complex numbers to real numbers only if their
+// imaginary part is zero.
+//
+// Slices and arrays convert to slices and arrays element by element, i.e.
+// []interface{} to []T; arrays may not be shorter than their source. Maps
+// convert to maps by converting keys and elements. Maps with string keys
+// convert to structs by setting exported fields named by keys, matched
+// exactly or ignoring case; keys that name no field are ignored. Other
+// values convert if Go converts them.
+//
+// Every failure is returned as a *ConversionError whose Path points to the
+// element of src that failed to convert and which responds to
+// errors.Is(err, ErrConvert). If o.CollectErrors is true errors of
+// compound values are returned as a MultiError of all errors. Reference
+// cycles in src return an error. If t is nil ErrInvalidParam is returned.
+func (o ConvertOptions) ConvertValue(src reflect.Value, t reflect.Type) (reflect.Value, error) {
+	if t == nil {
+		return reflect.Value{}, ErrInvalidParam
+	}
+	out := reflect.New(t).Elem()
+	if err := o.convertTo(src, out, make(map[visit]bool)); err != nil {
+		return reflect.Value{}, err
+	}
+	return out, nil
+}
+
+// convertTo converts v to settable out. Visited holds references being
+// converted.
+func (o ConvertOptions) convertTo(v, out reflect.Value, visited map[visit]bool) error {
+	t := out.Type()
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Invalid:
+		out.Set(reflect.Zero(t))
+		return nil
+	case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			out.Set(reflect.Zero(t))
+			return nil
+		}
+	}
+	if v.Type().AssignableTo(t) {
+		if !v.CanInterface() {
+			return convertFailure(v, t, ErrUnsupported)
+		}
+		out.Set(v)
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		elem := reflect.New(t.Elem())
+		if err := o.convertTo(v, elem.Elem(), visited); err != nil {
+			return err
+		}
+		out.Set(elem)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.Kind() == reflect.Slice && v.Len() == 0 {
+			break
+		}
+		key := visitOf(v)
+		if visited[key] {
+			return convertFailure(v, t, ErrUnsupported.Wrap("reference cycle"))
+		}
+		visited[key] = true
+		defer delete(visited, key)
+	}
+	if v.Kind() == reflect.Ptr {
+		return o.convertTo(v.Elem(), out, visited)
+	}
+	if v.Kind() == reflect.String && !runesOrBytes(t) {
+		return o.StringToValue(v.String(), out)
+	}
+	if t.Kind() == reflect.String && !runesOrBytes(v.Type()) {
+		s, err := ValueToString(v)
+		if err != nil {
+			return convertFailure(v, t, err)
+		}
+		out.SetString(s)
+		return nil
+	}
+	if isNumber(v.Kind()) && isNumber(t.Kind()) {
+		return convertNumber(v, out)
+	}
+	switch {
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) &&
+		(v.Kind() == reflect.Slice || v.Kind() == reflect.Array):
+		return o.convertElems(v, out, visited)
+	case t.Kind() == reflect.Map && v.Kind() == reflect.Map:
+		return o.convertMap(v, out, visited)
+	case t.Kind() == reflect.Struct && v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		return o.convertStruct(v, out, visited)
+	}
+	if convertible(v.Type(), t) && v.CanInterface() {
+		if cv, ok := convertValue(v, t); ok {
+			out.Set(cv)
+			return nil
+		}
+	}
+	return convertFailure(v, t, ErrUnsupported)
+}
+
+// convertElems converts slice or array v to slice or array out element by
+// element.
+func (o ConvertOptions) convertElems(v, out reflect.Value, visited map[visit]bool) error {
+	t := out.Type()
+	elems := reflect.New(t).Elem()
+	if t.Kind() == reflect.Slice {
+		elems.Set(reflect.MakeSlice(t, v.Len(), v.Len()))
+	} else if v.Len() > t.Len() {
+		return convertFailure(v, t, strconv.ErrRange)
+	}
+	ec := &errorCollector{collect: o.CollectErrors}
+	for i := 0; i < v.Len(); i++ {
+		if err := o.convertTo(v.Index(i), elems.Index(i), visited); err != nil {
+			if ec.add(conversionError(fmt.Sprintf("[%d]", i), "", nil, err)) {
+				break
+			}
+		}
+	}
+	if err := ec.result(); err != nil {
+		return err
+	}
+	out.Set(elems)
+	return nil
+}
+
+// convertMap converts map v to map out by converting keys and elements.
+func (o ConvertOptions) convertMap(v, out reflect.Value, visited map[visit]bool) error {
+	t := out.Type()
+	result := reflect.MakeMapWithSize(t, v.Len())
+	ec := &errorCollector{collect: o.CollectErrors}
+	for _, key := range sortedKeys(v) {
+		path := "[" + inputString(key) + "]"
+		k := reflect.New(t.Key()).Elem()
+		if err := o.convertTo(key, k, visited); err != nil {
+			if ec.add(conversionError(path, "", nil, err)) {
+				break
+			}
+			continue
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		if err := o.convertTo(v.MapIndex(key), elem, visited); err != nil {
+			if ec.add(conversionError(path, "", nil, err)) {
+				break
+			}
+			continue
+		}
+		result.SetMapIndex(k, elem)
+	}
+	if err := ec.result(); err != nil {
+		return err
+	}
+	out.Set(result)
+	return nil
+}
+
+// convertStruct converts map v with string keys to struct out by setting
+// fields named by keys.
+func (o ConvertOptions) convertStruct(v, out reflect.Value, visited map[visit]bool) error {
+	t := out.Type()
+	result := reflect.New(t).Elem()
+	ti := TypeInfoOf(t)
+	ec := &errorCollector{collect: o.CollectErrors}
+	for _, key := range sortedKeys(v) {
+		field, ok := ti.field(key.String(), StructOptions{})
+		if !ok {
+			for _, fi := range ti.fieldList(StructOptions{}) {
+				if strings.EqualFold(fi.Name, key.String()) {
+					field, ok = fi, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		fv, ok := fieldByIndexAlloc(result, field.Index, false)
+		if !ok {
+			continue
+		}
+		if err := o.convertTo(v.MapIndex(key), fv, visited); err != nil {
+			if ec.add(conversionError("["+key.String()+"]", "", nil, err)) {
+				break
+			}
+		}
+	}
+	if err := ec.result(); err != nil {
+		return err
+	}
+	out.Set(result)
+	return nil
+}
+
+// convertNumber converts number v to number out if the value is
+// representable by out.
+func convertNumber(v, out reflect.Value) error {
+	t := out.Type()
+	var (
+		i                      int64
+		u                      uint64
+		f                      float64
+		c                      complex128
+		isInt, isUint, isFloat bool
+	)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i = v.Int()
+		isInt = true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u = v.Uint()
+		isUint = true
+	case reflect.Float32, reflect.Float64:
+		f = v.Float()
+		isFloat = true
+	default:
+		c = v.Complex()
+		if imag(c) != 0 && !isComplex(t.Kind()) {
+			return convertFailure(v, t, strconv.ErrRange)
+		}
+		f = real(c)
+		isFloat = true
+	}
+	ok := true
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch {
+		case isUint:
+			ok = u <= math.MaxInt64
+			i = int64(u)
+		case isFloat:
+			ok = f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64
+			i = int64(f)
+		}
+		if ok = ok && !out.OverflowInt(i); ok {
+			out.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch {
+		case isInt:
+			ok = i >= 0
+			u = uint64(i)
+		case isFloat:
+			ok = f == math.Trunc(f) && f >= 0 && f < math.MaxUint64
+			u = uint64(f)
+		}
+		if ok = ok && !out.OverflowUint(u); ok {
+			out.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		switch {
+		case isInt:
+			f = float64(i)
+		case isUint:
+			f = float64(u)
+		}
+		if ok = !out.OverflowFloat(f); ok {
+			out.SetFloat(f)
+		}
+	default:
+		switch {
+		case isInt:
+			c = complex(float64(i), 0)
+		case isUint:
+			c = complex(float64(u), 0)
+		case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+			c = complex(f, 0)
+		}
+		if ok = !out.OverflowComplex(c); ok {
+			out.SetComplex(c)
+		}
+	}
+	if !ok {
+		return convertFailure(v, t, strconv.ErrRange)
+	}
+	return nil
+}
+
+// convertFailure returns a *ConversionError of converting v to t.
+func convertFailure(v reflect.Value, t reflect.Type, cause error) error {
+	return &ConversionError{Input: inputString(v), Target: t, Cause: cause}
+}
+
+// inputString returns v formatted for a *ConversionError.
+func inputString(v reflect.Value) string {
+	if s, err := ValueToString(v); err == nil {
+		return s
+	}
+	return v.Type().String()
+}
+
+// sortedKeys returns keys of map v sorted using CompareValues.
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return CompareValues(keys[i], keys[j]) < 0
+	})
+	return keys
+}
+
+// runesOrBytes returns true if t is a slice of bytes or runes.
+func runesOrBytes(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice {
+		return false
+	}
+	switch t.Elem().Kind() {
+	case reflect.Uint8, reflect.Int32:
+		return true
+	}
+	return false
+}
+
+// isNumber returns true if k is a kind of number.
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Complex128
+}
+
+// isComplex returns true if k is a kind of complex number.
+func isComplex(k reflect.Kind) bool {
+	return k == reflect.Complex64 || k == reflect.Complex128
+}
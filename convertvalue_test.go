@@ -0,0 +1,124 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type ConvertTarget struct {
+	Name  string
+	Port  uint16
+	Hosts []string
+	Inner *ConvertTarget
+	Since time.Time
+}
+
+func TestConvert(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	num := 42
+	tests := []struct {
+		in   interface{}
+		want interface{}
+	}{
+		{nil, 0},
+		{int64(42), int8(42)},
+		{uint64(255), uint8(255)},
+		{int8(-1), float32(-1)},
+		{float64(8080), uint16(8080)},
+		{complex(2, 0), 2},
+		{1.5, complex64(1.5)},
+		{"42", uint(42)},
+		{"true", true},
+		{"1,2,3", []int{1, 2, 3}},
+		{now.Format(time.RFC3339), now},
+		{42, "42"},
+		{[]int{1, 2}, "1,2"},
+		{now, now.Format(time.RFC3339)},
+		{"abc", []byte("abc")},
+		{[]rune("abc"), "abc"},
+		{&num, int64(42)},
+		{num, &num},
+		{[]interface{}{1, "2", 3.0}, []int{1, 2, 3}},
+		{[]interface{}{1, "2"}, [3]int8{1, 2, 0}},
+		{map[string]interface{}{"a": "1", "b": 2}, map[string]float64{"a": 1, "b": 2}},
+		{map[int]string{1: "a"}, map[string][]byte{"1": []byte("a")}},
+		{
+			map[string]interface{}{
+				"name":  "x",
+				"Port":  8080.0,
+				"hosts": []interface{}{"a", "b"},
+				"inner": map[string]interface{}{"name": "y"},
+				"since": now.Format(time.RFC3339),
+				"other": 1,
+			},
+			ConvertTarget{"x", 8080, []string{"a", "b"}, &ConvertTarget{Name: "y"}, now},
+		},
+		{time.Duration(5), int64(5)},
+	}
+	for i, test := range tests {
+		want := reflect.ValueOf(test.want)
+		got, err := Convert(test.in, want.Type())
+		if err != nil {
+			t.Fatalf("Convert failed at %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got.Interface(), test.want) {
+			t.Fatalf("Convert failed at %d: want %#v, got %#v", i, test.want, got)
+		}
+	}
+	if _, err := Convert(1, nil); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("Convert failed, expected invalid param error")
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		in    interface{}
+		out   interface{}
+		path  string
+		cause error
+	}{
+		{300, int8(0), "", strconv.ErrRange},
+		{-1, uint(0), "", strconv.ErrRange},
+		{uint64(math.MaxUint64), int64(0), "", strconv.ErrRange},
+		{1.5, 0, "", strconv.ErrRange},
+		{math.NaN(), 0, "", strconv.ErrRange},
+		{1e300, float32(0), "", strconv.ErrRange},
+		{complex(1, 1), 0.0, "", strconv.ErrRange},
+		{"x", 0, "", strconv.ErrSyntax},
+		{true, 0, "", ErrUnsupported},
+		{[]int{1, 2}, [1]int{}, "", strconv.ErrRange},
+		{[]interface{}{1, "x"}, []int{}, "[1]", strconv.ErrSyntax},
+		{map[string]interface{}{"a": []interface{}{"b"}}, map[string][]bool{}, "[a][0]", strconv.ErrSyntax},
+		{map[string]interface{}{"inner": map[string]interface{}{"port": -1}}, ConvertTarget{}, "[inner][port]", strconv.ErrRange},
+		{struct{}{}, 0, "", ErrUnsupported},
+	}
+	for i, test := range tests {
+		_, err := Convert(test.in, reflect.TypeOf(test.out))
+		var ce *ConversionError
+		if !errors.Is(err, ErrConvert) || !errors.As(err, &ce) {
+			t.Fatalf("Convert failed at %d, expected conversion error: %v", i, err)
+		}
+		if ce.Path != test.path || !errors.Is(err, test.cause) {
+			t.Fatalf("Convert failed at %d, bad error: %v", i, err)
+		}
+	}
+	m := map[string]interface{}{}
+	m["self"] = m
+	type Recursive map[string]Recursive
+	if _, err := Convert(m, reflect.TypeOf(Recursive{})); !errors.Is(err, ErrConvert) {
+		t.Fatal("Convert failed, expected error on cycle")
+	}
+	_, err := ConvertOptions{CollectErrors: true}.Convert([]interface{}{"x", 1, "y"}, reflect.TypeOf([]int{}))
+	var me MultiError
+	if !errors.As(err, &me) || len(me) != 2 {
+		t.Fatalf("Convert(CollectErrors) failed: %v", err)
+	}
+}
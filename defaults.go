@@ -0,0 +1,130 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"reflect"
+	"sync"
+)
+
+// SetDefaults sets zero fields of a struct pointed to by v to values parsed
+// from their "default" struct tags. See StructOptions.SetDefaults.
+func SetDefaults(v interface{}) error {
+	return StructOptions{}.SetDefaults(v)
+}
+
+// SetDefaults sets zero fields of a struct pointed to by v to values parsed
+// from their "default" struct tags using StringToValue, i.e.:
+//
+//	type Config struct {
+//		Port  int               `default:"8080"`
+//		Hosts []string          `default:"localhost,127.0.0.1"`
+//		Env   map[string]string `default:"mode=dev,debug=true"`
+//	}
+//
+// Nested structs are populated recursively and nil pointers to structs that
+// define defaults, directly or in nested structs, are allocated. Pointers to
+// types already being populated are not allocated to stop on recursive types.
+//
+// If a default value fails to parse the error wraps ErrDefault.
+func (o StructOptions) SetDefaults(v interface{}) error {
+	pv := reflect.ValueOf(v)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() || pv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidParam
+	}
+	return o.setDefaults("", pv.Elem(), make(map[reflect.Type]bool))
+}
+
+// setDefaults sets defaults of struct v at path. Active holds types of
+// structs being populated.
+func (o StructOptions) setDefaults(path string, v reflect.Value, active map[reflect.Type]bool) error {
+	active[v.Type()] = true
+	defer delete(active, v.Type())
+	for _, field := range TypeInfoOf(v.Type()).fieldList(o) {
+		fieldpath := joinPath(path, field.Name)
+		def, hasdef := field.Tag.Lookup("default")
+		fv, ok := fieldByIndex(v, field.Index, o.AllowUnexported)
+		if !ok {
+			if !hasdef && !o.hasDefaults(field.Type) {
+				continue
+			}
+			if fv, ok = fieldByIndexAlloc(v, field.Index, o.AllowUnexported); !ok {
+				continue
+			}
+		}
+		if !fv.CanSet() {
+			continue
+		}
+		if hasdef && fv.IsZero() {
+			if err := StringToValue(def, fv); err != nil {
+				return ErrDefault.WrapCauseArgs(err, fieldpath)
+			}
+		}
+		if fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct {
+			if fv.IsNil() {
+				if active[fv.Type().Elem()] || !o.hasDefaults(fv.Type().Elem()) {
+					continue
+				}
+				fv.Set(reflect.New(fv.Type().Elem()))
+			} else if active[fv.Type().Elem()] {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() != reflect.Struct {
+			continue
+		}
+		if err := o.setDefaults(fieldpath, fv, active); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultsKey is the key of hasDefaults cache.
+type defaultsKey struct {
+	typ  reflect.Type
+	opts StructOptions
+}
+
+// defaultsCache caches results of hasDefaults.
+var defaultsCache sync.Map
+
+// hasDefaults returns true if t is a struct or a pointer to a struct that
+// has fields with default tags, directly or in nested structs.
+func (o StructOptions) hasDefaults(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	key := defaultsKey{t, o}
+	if cached, ok := defaultsCache.Load(key); ok {
+		return cached.(bool)
+	}
+	result := o.findDefaults(t, make(map[reflect.Type]bool))
+	defaultsCache.Store(key, result)
+	return result
+}
+
+// findDefaults is the implementation of hasDefaults. Visited holds visited
+// struct types.
+func (o StructOptions) findDefaults(t reflect.Type, visited map[reflect.Type]bool) bool {
+	visited[t] = true
+	for _, field := range TypeInfoOf(t).fieldList(o) {
+		if _, ok := field.Tag.Lookup("default"); ok {
+			return true
+		}
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && !visited[ft] && o.findDefaults(ft, visited) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type DefaultsDB struct {
+	Host string `default:"localhost"`
+	Port int    `default:"5432"`
+}
+
+type DefaultsNoDefaults struct {
+	Name string
+}
+
+type DefaultsNode struct {
+	Name string `default:"node"`
+	Next *DefaultsNode
+}
+
+type DefaultsConfig struct {
+	Name    string            `default:"app"`
+	Port    int               `default:"8080"`
+	Ratio   float64           `default:"0.5"`
+	Debug   bool              `default:"true"`
+	Hosts   []string          `default:"a,b,c"`
+	Env     map[string]string `default:"mode=dev,debug=true"`
+	Limit   *int              `default:"10"`
+	Set     int               `default:"1"`
+	DB      DefaultsDB
+	Replica *DefaultsDB
+	Other   *DefaultsNoDefaults
+	Node    *DefaultsNode
+}
+
+func TestSetDefaults(t *testing.T) {
+	cfg := &DefaultsConfig{Set: 2}
+	if err := SetDefaults(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "app" || cfg.Port != 8080 || cfg.Ratio != 0.5 || !cfg.Debug || cfg.Set != 2 {
+		t.Fatalf("SetDefaults failed: %#v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Hosts, []string{"a", "b", "c"}) {
+		t.Fatal("SetDefaults failed, bad slice")
+	}
+	if !reflect.DeepEqual(cfg.Env, map[string]string{"mode": "dev", "debug": "true"}) {
+		t.Fatal("SetDefaults failed, bad map")
+	}
+	if cfg.Limit == nil || *cfg.Limit != 10 {
+		t.Fatal("SetDefaults failed, bad pointer")
+	}
+	if cfg.DB != (DefaultsDB{"localhost", 5432}) {
+		t.Fatal("SetDefaults failed, bad nested struct")
+	}
+	if cfg.Replica == nil || *cfg.Replica != (DefaultsDB{"localhost", 5432}) {
+		t.Fatal("SetDefaults failed, bad nested pointer")
+	}
+	if cfg.Other != nil {
+		t.Fatal("SetDefaults failed, allocated pointer without defaults")
+	}
+	if cfg.Node == nil || cfg.Node.Name != "node" || cfg.Node.Next != nil {
+		t.Fatal("SetDefaults failed, bad recursive type")
+	}
+}
+
+func TestSetDefaultsErrors(t *testing.T) {
+	if err := SetDefaults(DefaultsDB{}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("SetDefaults failed, expected invalid param error")
+	}
+	type Test struct {
+		Nested struct {
+			Port int `default:"eighty"`
+		}
+	}
+	if err := SetDefaults(&Test{}); !errors.Is(err, ErrDefault) {
+		t.Fatal("SetDefaults failed, expected default error")
+	}
+}
+
+func TestSetDefaultsFlatten(t *testing.T) {
+	type Test struct {
+		*DefaultsDB
+		Name string `default:"test"`
+	}
+	test := &Test{}
+	if err := (StructOptions{Flatten: true}).SetDefaults(test); err != nil {
+		t.Fatal(err)
+	}
+	if test.DefaultsDB == nil || test.Host != "localhost" || test.Name != "test" {
+		t.Fatal("SetDefaults(Flatten) failed")
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI color escape sequences used by Dump.
+const (
+	colorReset  = "\x1b[0m"
+	colorType   = "\x1b[36m"
+	colorField  = "\x1b[1m"
+	colorString = "\x1b[32m"
+	colorNumber = "\x1b[33m"
+	colorMeta   = "\x1b[90m"
+)
+
+// DumpOptions define how Dump prints values.
+type DumpOptions struct {
+	// MaxDepth, if greater than zero, limits the depth of printed compound
+	// values. Values deeper than MaxDepth are printed as "...".
+	MaxDepth int
+	// MaxLen, if greater than zero, limits the number of printed elements of
+	// arrays, slices and maps and the number of printed runes of strings.
+	MaxLen int
+	// Addresses, if true, prints addresses of pointers and addressable values.
+	Addresses bool
+	// Color, if true, colors output using ANSI escape sequences.
+	Color bool
+	// Indent is the string used to indent nested values. If empty, two
+	// spaces are used.
+	Indent string
+}
+
+// Dump writes a recursive, indented tree representation of v to w using
+// options opts. Struct fields are printed with their names, types, tags and
+// whether they are exported and settable. Unexported fields are printed as
+// well. Map entries are printed sorted by key.
+//
+// Pointers, maps and slices already printed are printed as back-references
+// to the path of the first occurence, i.e. "<ref Parent.Next>", which stops
+// on cycles. The path of v itself is "root".
+func Dump(w io.Writer, v interface{}, opts DumpOptions) error {
+	if w == nil {
+		return ErrInvalidParam
+	}
+	_, err := io.WriteString(w, Sdump(v, opts))
+	return err
+}
+
+// Sdump returns the output of Dump as a string.
+func Sdump(v interface{}, opts DumpOptions) string {
+	return SdumpValue(reflect.ValueOf(v), opts)
+}
+
+// SdumpValue returns the output of Dump of a reflect.Value as a string.
+func SdumpValue(v reflect.Value, opts DumpOptions) string {
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	d := &dumper{opts: opts, refs: make(map[visit]string)}
+	d.dump(v, 0, "", "", nil)
+	return d.buf.String()
+}
+
+// dumper is the state of a Dump call.
+type dumper struct {
+	opts DumpOptions
+	buf  bytes.Buffer
+	// refs map printed pointers, maps and slices to paths where they were
+	// first printed.
+	refs map[visit]string
+}
+
+// color writes s colored with color c if coloring is enabled.
+func (d *dumper) color(c, s string) {
+	if d.opts.Color {
+		d.buf.WriteString(c)
+		d.buf.WriteString(s)
+		d.buf.WriteString(colorReset)
+		return
+	}
+	d.buf.WriteString(s)
+}
+
+// dump writes v at depth. Label is the field name, index or key of v in its
+// parent, path is the path to v and field is set if v is a struct field.
+func (d *dumper) dump(v reflect.Value, depth int, label, path string, field *reflect.StructField) {
+	d.buf.WriteString(strings.Repeat(d.opts.Indent, depth))
+	if label != "" {
+		d.color(colorField, label)
+		d.buf.WriteString(": ")
+	}
+	if !v.IsValid() {
+		d.color(colorMeta, "<invalid>")
+		d.buf.WriteByte('\n')
+		return
+	}
+	d.color(colorType, v.Type().String())
+	if field != nil {
+		if field.Tag != "" {
+			d.buf.WriteByte(' ')
+			d.color(colorMeta, "`"+string(field.Tag)+"`")
+		}
+		flags := []string{"exported"}
+		if field.PkgPath != "" {
+			flags[0] = "unexported"
+		}
+		if v.CanSet() {
+			flags = append(flags, "settable")
+		}
+		d.buf.WriteByte(' ')
+		d.color(colorMeta, "("+strings.Join(flags, ", ")+")")
+	}
+	if d.opts.Addresses && v.CanAddr() {
+		d.buf.WriteByte(' ')
+		d.color(colorMeta, fmt.Sprintf("@%#x", v.UnsafeAddr()))
+	}
+	// Dereference pointers and interfaces.
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			d.buf.WriteString(" = ")
+			d.color(colorMeta, "nil")
+			d.buf.WriteByte('\n')
+			return
+		}
+		if v.Kind() == reflect.Ptr {
+			if d.ref(v, path) {
+				return
+			}
+			if d.opts.Addresses {
+				d.buf.WriteString(" -> ")
+				d.color(colorMeta, fmt.Sprintf("%#x", v.Pointer()))
+			}
+		}
+		v = v.Elem()
+		if v.Kind() != reflect.Ptr {
+			d.buf.WriteString(" -> ")
+			d.color(colorType, v.Type().String())
+		}
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map:
+	default:
+		d.buf.WriteString(" = ")
+		d.scalar(v)
+		d.buf.WriteByte('\n')
+		return
+	}
+	if v.Kind() == reflect.Slice && v.IsNil() || v.Kind() == reflect.Map && v.IsNil() {
+		d.buf.WriteString(" = ")
+		d.color(colorMeta, "nil")
+		d.buf.WriteByte('\n')
+		return
+	}
+	if v.Kind() != reflect.Struct {
+		d.buf.WriteByte(' ')
+		d.color(colorMeta, fmt.Sprintf("len=%d", v.Len()))
+	}
+	if v.Kind() != reflect.Struct && v.Kind() != reflect.Array && v.Len() > 0 && d.ref(v, path) {
+		return
+	}
+	if d.opts.MaxDepth > 0 && depth >= d.opts.MaxDepth {
+		d.buf.WriteString(" {...}\n")
+		return
+	}
+	d.buf.WriteString(" {\n")
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			sf := v.Type().Field(i)
+			d.dump(v.Field(i), depth+1, sf.Name, joinPath(path, sf.Name), &sf)
+		}
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if d.truncated(i, v.Len(), depth+1) {
+				break
+			}
+			label := fmt.Sprintf("[%d]", i)
+			d.dump(v.Index(i), depth+1, label, path+label, nil)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return CompareValues(keys[i], keys[j]) < 0
+		})
+		for i, key := range keys {
+			if d.truncated(i, len(keys), depth+1) {
+				break
+			}
+			label := "[" + d.key(key) + "]"
+			d.dump(v.MapIndex(key), depth+1, label, path+label, nil)
+		}
+	}
+	d.buf.WriteString(strings.Repeat(d.opts.Indent, depth))
+	d.buf.WriteString("}\n")
+}
+
+// ref writes a back-reference and returns true if pointer, map or slice v
+// was already printed. Otherwise records v as printed at path.
+func (d *dumper) ref(v reflect.Value, path string) bool {
+	key := visitOf(v)
+	ref, ok := d.refs[key]
+	if !ok {
+		d.refs[key] = path
+		return false
+	}
+	if ref == "" {
+		ref = "root"
+	}
+	d.buf.WriteByte(' ')
+	d.color(colorMeta, "<ref "+ref+">")
+	d.buf.WriteByte('\n')
+	return true
+}
+
+// truncated writes a truncation line and returns true if element i of n
+// elements exceeds MaxLen.
+func (d *dumper) truncated(i, n, depth int) bool {
+	if d.opts.MaxLen <= 0 || i < d.opts.MaxLen {
+		return false
+	}
+	d.buf.WriteString(strings.Repeat(d.opts.Indent, depth))
+	d.color(colorMeta, fmt.Sprintf("... (%d more)", n-i))
+	d.buf.WriteByte('\n')
+	return true
+}
+
+// key returns map key v formatted as a label.
+func (d *dumper) key(v reflect.Value) string {
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return scalarString(v)
+	}
+	if v.CanInterface() {
+		return fmt.Sprintf("%v", v.Interface())
+	}
+	return v.Type().String()
+}
+
+// scalar writes a non-compound value v.
+func (d *dumper) scalar(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		if d.opts.MaxLen > 0 && utf8.RuneCountInString(s) > d.opts.MaxLen {
+			s = string([]rune(s)[:d.opts.MaxLen])
+			d.color(colorString, strconv.Quote(s))
+			d.color(colorMeta, "...")
+			return
+		}
+		d.color(colorString, strconv.Quote(s))
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if v.IsNil() {
+			d.color(colorMeta, "nil")
+			return
+		}
+		d.color(colorMeta, fmt.Sprintf("%#x", v.Pointer()))
+	default:
+		d.color(colorNumber, scalarString(v))
+	}
+}
+
+// scalarString returns a bool or a number v formatted as a string.
+// Values of other kinds return an empty string.
+func scalarString(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Complex64:
+		return strconv.FormatComplex(v.Complex(), 'g', -1, 64)
+	case reflect.Complex128:
+		return strconv.FormatComplex(v.Complex(), 'g', -1, 128)
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type DumpNode struct {
+	Name   string `json:"name"`
+	Tags   []string
+	Attrs  map[string]interface{}
+	Next   *DumpNode
+	hidden int
+}
+
+func TestDump(t *testing.T) {
+	node := &DumpNode{
+		Name:   "root",
+		Tags:   []string{"a", "b"},
+		Attrs:  map[string]interface{}{"b": 2, "a": "x"},
+		hidden: 42,
+	}
+	node.Next = node
+	buf := &bytes.Buffer{}
+	if err := Dump(buf, node, DumpOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want := `*reflectex.DumpNode -> reflectex.DumpNode {
+  Name: string ` + "`json:\"name\"`" + ` (exported, settable) = "root"
+  Tags: []string (exported, settable) len=2 {
+    [0]: string = "a"
+    [1]: string = "b"
+  }
+  Attrs: map[string]interface {} (exported, settable) len=2 {
+    ["a"]: interface {} -> string = "x"
+    ["b"]: interface {} -> int = 2
+  }
+  Next: *reflectex.DumpNode (exported, settable) <ref root>
+  hidden: int (unexported) = 42
+}
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("Dump failed:\nwant:\n%s\ngot:\n%s", want, got)
+	}
+	if err := Dump(nil, node, DumpOptions{}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("Dump failed, expected invalid param error")
+	}
+}
+
+func TestDumpCycle(t *testing.T) {
+	m := map[string]interface{}{}
+	m["self"] = m
+	s := []interface{}{nil}
+	s[0] = s
+	want := `map[string]interface {} len=1 {
+  ["self"]: interface {} -> map[string]interface {} len=1 <ref root>
+}
+`
+	if out := Sdump(m, DumpOptions{}); out != want {
+		t.Fatalf("Sdump failed:\n%s", out)
+	}
+	if out := Sdump(s, DumpOptions{}); !strings.Contains(out, "<ref root>") {
+		t.Fatalf("Sdump failed:\n%s", out)
+	}
+}
+
+func TestSdumpTruncate(t *testing.T) {
+	type Test struct {
+		Name   string
+		Nums   []int
+		Nested struct {
+			Deep struct {
+				Value int
+			}
+		}
+	}
+	out := Sdump(Test{Name: "abcdef", Nums: []int{1, 2, 3, 4}}, DumpOptions{MaxDepth: 2, MaxLen: 2, Indent: "\t"})
+	for _, want := range []string{
+		"\tName: string (exported) = \"ab\"...\n",
+		"\t\t... (2 more)\n",
+		"\t\tDeep: struct { Value int } (exported) {...}\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("Sdump failed, missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestSdumpOptions(t *testing.T) {
+	if out := Sdump(nil, DumpOptions{}); out != "<invalid>\n" {
+		t.Fatalf("Sdump failed: %q", out)
+	}
+	if out := Sdump(42, DumpOptions{Color: true}); out != colorType+"int"+colorReset+" = "+colorNumber+"42"+colorReset+"\n" {
+		t.Fatalf("Sdump failed: %q", out)
+	}
+	n := 1
+	if out := Sdump(&n, DumpOptions{Addresses: true}); !strings.Contains(out, "-> 0x") {
+		t.Fatalf("Sdump failed: %q", out)
+	}
+	if out := SdumpValue(reflect.ValueOf(map[int]bool(nil)), DumpOptions{}); out != "map[int]bool = nil\n" {
+		t.Fatalf("Sdump failed: %q", out)
+	}
+}
+
+func TestShowInvalid(t *testing.T) {
+	Show(reflect.Value{})
+}
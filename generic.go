@@ -0,0 +1,36 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import "reflect"
+
+// Parse returns s parsed to a value of type T using StringToValue.
+// On error a zero T is returned with the error.
+func Parse[T any](s string) (T, error) {
+	var v T
+	if err := StringToValue(s, reflect.ValueOf(&v).Elem()); err != nil {
+		var zero T
+		return zero, err
+	}
+	return v, nil
+}
+
+// Compare compares a and b using CompareValues.
+func Compare[T any](a, b T) int {
+	return CompareValues(reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem())
+}
+
+// Clone returns a deep copy of v using CloneValue.
+func Clone[T any](v T) T {
+	var c T
+	reflect.ValueOf(&c).Elem().Set(CloneValue(reflect.ValueOf(&v).Elem()))
+	return c
+}
+
+// Copy copies fields of struct src to struct dst using LazyStructCopy.
+// Src may be a struct or a pointer to one.
+func Copy[S, D any](src S, dst *D) error {
+	return LazyStructCopy(src, dst)
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	n, err := Parse[int8]("42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 42 {
+		t.Fatal("Parse failed")
+	}
+	m, err := Parse[map[string][]int]("a=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, map[string][]int{"a": {1}}) {
+		t.Fatal("Parse failed")
+	}
+	if n, err := Parse[int]("x"); !errors.Is(err, ErrConvert) || n != 0 {
+		t.Fatal("Parse failed, expected conversion error")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	if Compare(1, 2) != -1 || Compare("b", "a") != 1 || Compare([]int{1}, []int{1}) != 0 {
+		t.Fatal("Compare failed")
+	}
+	var a, b error
+	if Compare(a, b) != 0 {
+		t.Fatal("Compare failed on nil interfaces")
+	}
+}
+
+func TestClone(t *testing.T) {
+	in := map[string][]int{"a": {1}}
+	out := Clone(in)
+	out["a"][0] = 2
+	if in["a"][0] != 1 {
+		t.Fatal("Clone failed, copy not deep")
+	}
+	var err error
+	if Clone(err) != nil {
+		t.Fatal("Clone failed on nil interface")
+	}
+}
+
+func TestCopy(t *testing.T) {
+
+	type (
+		Src struct {
+			Name  string
+			Count int
+		}
+
+		Dst struct {
+			Name string
+		}
+	)
+
+	dst := &Dst{}
+	if err := Copy(Src{"name", 1}, dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Name != "name" {
+		t.Fatal("Copy failed")
+	}
+	if err := Copy(Src{}, (*Dst)(nil)); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("Copy failed, expected invalid param error")
+	}
+}
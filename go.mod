@@ -1,6 +1,6 @@
 module github.com/vedranvuk/reflectex
 
-go 1.14
+go 1.18
 
 require github.com/vedranvuk/errorex v0.3.2
 
@@ -0,0 +1,297 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeType is the reflect.Type of time.Time.
+var timeType = reflect.TypeOf(time.Time{})
+
+// GoStringHook returns a Go literal of v and true or false if v should be
+// formatted by default rules.
+type GoStringHook func(v reflect.Value) (string, bool)
+
+// GoStringOptions define how GoString formats values.
+type GoStringOptions struct {
+	// Hook, if not nil, is called for every formatted value before the
+	// built-in hooks and default rules.
+	Hook GoStringHook
+}
+
+// GoString returns a Go literal of v which compiles to an equal value,
+// i.e. `&pkg.Config{Name: "x", Ports: []int{1, 2}}`. See
+// GoStringOptions.GoString.
+func GoString(v interface{}) string {
+	return GoStringOptions{}.GoString(v)
+}
+
+// GoString returns a Go literal of v which compiles to an equal value.
+//
+// Types are package-qualified as reported by reflect, i.e. "pkg.Config",
+// so the output compiles where the packages are imported by name. Values of
+// types other than default literal types are converted explicitly when
+// their type is not implied by the context, i.e. int8(42).
+//
+// Struct literals omit zero and unexported fields. Map entries are sorted
+// by key. Pointers to non-composite values are expressed as func literals.
+// Values of time.Time are expressed as calls to time.Date. Reference
+// cycles, funcs and chans that are not nil can not be expressed and are
+// formatted as nil followed by a comment.
+func (o GoStringOptions) GoString(v interface{}) string {
+	return o.GoStringValue(reflect.ValueOf(v))
+}
+
+// GoStringValue returns a Go literal of v which compiles to an equal value.
+// See GoString.
+func (o GoStringOptions) GoStringValue(v reflect.Value) string {
+	sb := &strings.Builder{}
+	o.literal(sb, v, nil, make(map[visit]bool))
+	return sb.String()
+}
+
+// literal writes a literal of v to sb. Ctx is the type implied by the
+// context v is formatted in, nil if none. Visited holds pointers, maps and
+// slices being formatted.
+func (o GoStringOptions) literal(sb *strings.Builder, v reflect.Value, ctx reflect.Type, visited map[visit]bool) {
+	if !v.IsValid() {
+		sb.WriteString("nil")
+		return
+	}
+	if o.Hook != nil {
+		if s, ok := o.Hook(v); ok {
+			sb.WriteString(s)
+			return
+		}
+	}
+	if s, ok := goStringTime(v); ok {
+		sb.WriteString(s)
+		return
+	}
+	t := v.Type()
+	switch v.Kind() {
+	case reflect.Bool:
+		o.basic(sb, strconv.FormatBool(v.Bool()), t, ctx, reflect.Bool)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		o.basic(sb, strconv.FormatInt(v.Int(), 10), t, ctx, reflect.Int)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		o.basic(sb, strconv.FormatUint(v.Uint(), 10), t, ctx, reflect.Int)
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			ctx = nil
+		}
+		o.basic(sb, floatLiteral(f, t.Bits()), t, ctx, reflect.Float64)
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		if cmplx.IsNaN(c) || cmplx.IsInf(c) {
+			ctx = nil
+		}
+		lit := fmt.Sprintf("complex(%s, %s)", floatLiteral(real(c), t.Bits()/2), floatLiteral(imag(c), t.Bits()/2))
+		o.basic(sb, lit, t, ctx, reflect.Complex128)
+	case reflect.String:
+		o.basic(sb, strconv.Quote(v.String()), t, ctx, reflect.String)
+	case reflect.Interface:
+		if v.IsNil() {
+			o.nilLiteral(sb, t, ctx)
+			return
+		}
+		o.literal(sb, v.Elem(), t, visited)
+	case reflect.Ptr:
+		o.pointer(sb, v, ctx, visited)
+	case reflect.Struct:
+		sb.WriteString(t.String())
+		sb.WriteByte('{')
+		n := 0
+		for _, field := range TypeInfoOf(t).Fields {
+			fv := v.Field(field.Index[0])
+			if fv.IsZero() {
+				continue
+			}
+			if n > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(field.Name)
+			sb.WriteString(": ")
+			o.literal(sb, fv, field.Type, visited)
+			n++
+		}
+		sb.WriteByte('}')
+	case reflect.Array, reflect.Slice:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			o.nilLiteral(sb, t, ctx)
+			return
+		}
+		if v.Kind() == reflect.Slice {
+			if !o.enter(sb, v, visited) {
+				return
+			}
+			defer o.leave(v, visited)
+		}
+		sb.WriteString(t.String())
+		sb.WriteByte('{')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			o.literal(sb, v.Index(i), t.Elem(), visited)
+		}
+		sb.WriteByte('}')
+	case reflect.Map:
+		if v.IsNil() {
+			o.nilLiteral(sb, t, ctx)
+			return
+		}
+		if !o.enter(sb, v, visited) {
+			return
+		}
+		defer o.leave(v, visited)
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return CompareValues(keys[i], keys[j]) < 0
+		})
+		sb.WriteString(t.String())
+		sb.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			o.literal(sb, key, t.Key(), visited)
+			sb.WriteString(": ")
+			o.literal(sb, v.MapIndex(key), t.Elem(), visited)
+		}
+		sb.WriteByte('}')
+	default:
+		if v.IsNil() {
+			o.nilLiteral(sb, t, ctx)
+			return
+		}
+		sb.WriteString("nil /* ")
+		sb.WriteString(t.String())
+		sb.WriteString(" */")
+	}
+}
+
+// basic writes a basic literal lit of type t to sb, converting it to t
+// explicitly unless ctx is t or unless t is the default type def of the
+// untyped literal and ctx does not imply a type. Non-constant literals such
+// as math.NaN() are passed with a nil ctx to force conversion.
+func (o GoStringOptions) basic(sb *strings.Builder, lit string, t, ctx reflect.Type, def reflect.Kind) {
+	if t == ctx || (t.Name() == def.String() && t.PkgPath() == "" && (ctx == nil || ctx.Kind() == reflect.Interface)) {
+		sb.WriteString(lit)
+		return
+	}
+	sb.WriteString(t.String())
+	sb.WriteByte('(')
+	sb.WriteString(lit)
+	sb.WriteByte(')')
+}
+
+// nilLiteral writes a nil literal of type t to sb, converting it explicitly
+// unless ctx is t.
+func (o GoStringOptions) nilLiteral(sb *strings.Builder, t, ctx reflect.Type) {
+	if t == ctx || t.Kind() == reflect.Interface {
+		sb.WriteString("nil")
+		return
+	}
+	sb.WriteByte('(')
+	sb.WriteString(t.String())
+	sb.WriteString(")(nil)")
+}
+
+// pointer writes a literal of pointer v to sb.
+func (o GoStringOptions) pointer(sb *strings.Builder, v reflect.Value, ctx reflect.Type, visited map[visit]bool) {
+	t := v.Type()
+	if v.IsNil() {
+		o.nilLiteral(sb, t, ctx)
+		return
+	}
+	if !o.enter(sb, v, visited) {
+		return
+	}
+	defer o.leave(v, visited)
+	switch t.Elem().Kind() {
+	case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map:
+		if _, ok := goStringTime(v.Elem()); !ok {
+			sb.WriteByte('&')
+			o.literal(sb, v.Elem(), t.Elem(), visited)
+			return
+		}
+	}
+	sb.WriteString("func() ")
+	sb.WriteString(t.String())
+	sb.WriteString(" { v := ")
+	o.literal(sb, v.Elem(), nil, visited)
+	sb.WriteString("; return &v }()")
+}
+
+// enter marks pointer, map or slice v as being formatted and returns true
+// or writes a cycle comment to sb and returns false if v is already being
+// formatted.
+func (o GoStringOptions) enter(sb *strings.Builder, v reflect.Value, visited map[visit]bool) bool {
+	if v.Kind() != reflect.Ptr && v.Len() == 0 {
+		return true
+	}
+	key := visitOf(v)
+	if visited[key] {
+		sb.WriteString("nil /* cycle */")
+		return false
+	}
+	visited[key] = true
+	return true
+}
+
+// leave unmarks pointer, map or slice v marked by enter.
+func (o GoStringOptions) leave(v reflect.Value, visited map[visit]bool) {
+	if v.Kind() != reflect.Ptr && v.Len() == 0 {
+		return
+	}
+	delete(visited, visitOf(v))
+}
+
+// floatLiteral returns a float literal of f of bit size bits that keeps
+// a decimal point or an exponent so it is not taken for an int.
+func floatLiteral(f float64, bits int) string {
+	switch {
+	case math.IsNaN(f):
+		return "math.NaN()"
+	case math.IsInf(f, 1):
+		return "math.Inf(1)"
+	case math.IsInf(f, -1):
+		return "math.Inf(-1)"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s
+}
+
+// goStringTime returns a time.Date call literal of v if v is a time.Time.
+func goStringTime(v reflect.Value) (string, bool) {
+	if v.Type() != timeType || !v.CanInterface() {
+		return "", false
+	}
+	t := v.Interface().(time.Time)
+	var loc string
+	switch name, offset := t.Zone(); {
+	case t.Location() == time.UTC:
+		loc = "time.UTC"
+	case t.Location() == time.Local:
+		loc = "time.Local"
+	default:
+		loc = fmt.Sprintf("time.FixedZone(%q, %d)", name, offset)
+	}
+	return fmt.Sprintf("time.Date(%d, time.%s, %d, %d, %d, %d, %d, %s)",
+		t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc), true
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type GoStringInt int
+
+type GoStringConfig struct {
+	Name    string
+	Ports   []int
+	Weights map[string]float64
+	Level   GoStringInt
+	Any     interface{}
+	Parent  *GoStringConfig
+	Limit   *int
+	Created time.Time
+	Small   int8
+	Nil     []string
+	hidden  bool
+}
+
+func TestGoString(t *testing.T) {
+	limit := 10
+	in := &GoStringConfig{
+		Name:    "x",
+		Ports:   []int{1, 2},
+		Weights: map[string]float64{"b": 2, "a": 0.5},
+		Level:   3,
+		Any:     uint8(7),
+		Parent:  &GoStringConfig{Name: "parent"},
+		Limit:   &limit,
+		Created: time.Date(2020, time.March, 4, 5, 6, 7, 8, time.UTC),
+		Small:   -1,
+		hidden:  true,
+	}
+	want := `&reflectex.GoStringConfig{Name: "x", Ports: []int{1, 2}, ` +
+		`Weights: map[string]float64{"a": 0.5, "b": 2.0}, Level: 3, Any: uint8(7), ` +
+		`Parent: &reflectex.GoStringConfig{Name: "parent"}, ` +
+		`Limit: func() *int { v := 10; return &v }(), ` +
+		`Created: time.Date(2020, time.March, 4, 5, 6, 7, 8, time.UTC), Small: -1}`
+	if got := GoString(in); got != want {
+		t.Fatalf("GoString failed:\nwant %s\ngot  %s", want, got)
+	}
+}
+
+func TestGoStringBasic(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{nil, "nil"},
+		{42, "42"},
+		{int8(42), "int8(42)"},
+		{GoStringInt(1), "reflectex.GoStringInt(1)"},
+		{1.0, "1.0"},
+		{float32(0.5), "float32(0.5)"},
+		{math.Inf(1), "math.Inf(1)"},
+		{[]float32{float32(math.NaN())}, "[]float32{float32(math.NaN())}"},
+		{2 + 3i, "complex(2.0, 3.0)"},
+		{true, "true"},
+		{"a\"b", `"a\"b"`},
+		{[]string(nil), "([]string)(nil)"},
+		{[2]bool{true}, "[2]bool{true, false}"},
+		{map[int][]int{2: nil, 1: {1}}, "map[int][]int{1: []int{1}, 2: nil}"},
+		{[]interface{}{1, "a", nil}, `[]interface {}{1, "a", nil}`},
+		{struct{ A int }{1}, "struct { A int }{A: 1}"},
+		{func() {}, "nil /* func() */"},
+		{func() *int8 { v := int8(1); return &v }(), "func() *int8 { v := int8(1); return &v }()"},
+	}
+	for _, test := range tests {
+		if got := GoString(test.in); got != test.want {
+			t.Fatalf("GoString failed: want %s, got %s", test.want, got)
+		}
+	}
+}
+
+func TestGoStringCycle(t *testing.T) {
+	cfg := &GoStringConfig{}
+	cfg.Parent = cfg
+	if got := GoString(cfg); got != "&reflectex.GoStringConfig{Parent: nil /* cycle */}" {
+		t.Fatalf("GoString failed: %s", got)
+	}
+	m := map[string]interface{}{}
+	m["self"] = m
+	if got := GoString(m); got != `map[string]interface {}{"self": nil /* cycle */}` {
+		t.Fatalf("GoString failed: %s", got)
+	}
+	s := []interface{}{nil}
+	s[0] = s
+	if got := GoString(s); got != `[]interface {}{nil /* cycle */}` {
+		t.Fatalf("GoString failed: %s", got)
+	}
+}
+
+func TestGoStringHook(t *testing.T) {
+	opts := GoStringOptions{
+		Hook: func(v reflect.Value) (string, bool) {
+			if v.Type() == reflect.TypeOf(GoStringInt(0)) {
+				return "reflectex.Level", true
+			}
+			return "", false
+		},
+	}
+	if got := opts.GoString([]GoStringInt{1}); got != "[]reflectex.GoStringInt{reflectex.Level}" {
+		t.Fatalf("GoString(Hook) failed: %s", got)
+	}
+}
@@ -0,0 +1,481 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// INIOptions define how LoadINI and SaveINI map INI files to structs.
+type INIOptions struct {
+	// StructOptions define how struct fields are treated.
+	StructOptions
+	// Tag is the struct tag key whose name overrides the name of a section
+	// or a key of a field. If empty, "ini" is used. Fields tagged "-" are
+	// excluded.
+	Tag string
+	// CaseInsensitive, if true, matches sections and keys to field names
+	// ignoring case if no name matches exactly.
+	CaseInsensitive bool
+	// CollectErrors, if true, makes LoadINI load all keys and return all
+	// errors as a MultiError instead of returning the first error.
+	CollectErrors bool
+}
+
+// LoadINI loads an INI file from r into a struct pointed to by out using
+// zero INIOptions. See INIOptions.LoadINI.
+func LoadINI(r io.Reader, out interface{}) error {
+	return INIOptions{}.LoadINI(r, out)
+}
+
+// LoadINI loads an INI file from r into a struct pointed to by out.
+// Values are converted using StringToValue. The syntax is:
+//
+//	; Comment lines start with a semicolon
+//	# or a number sign.
+//	name = app
+//	hosts = a,b
+//
+//	[server]
+//	port: 8080
+//	motd = "  quoted values are unquoted  "
+//	tags = a
+//	tags = b
+//	description = long values continue \
+//	              on next lines
+//
+//	[server.tls]
+//	cert = cert.pem
+//
+// Keys before the first section set fields of out. Sections set fields of
+// nested structs, named by their tag or by their name, with dots
+// separating names of nested sections. A section naming a map sets map
+// entries from its' keys. Nil pointers along the way are allocated.
+//
+// Keys are separated from values by the first equal sign or colon. Values
+// are trimmed of spaces and unquoted as Go strings if double quoted. A
+// backslash at the end of a line joins the next line to it. Repeated keys
+// fill slices and arrays, one element per value; a single value fills
+// them using the StringToValue syntax, i.e. "a,b". Slices of bytes or runes
+// are set to a value as text. Other fields are set
+// to the last value of a repeated key. Sections and keys that do not
+// address a settable field are ignored.
+//
+// Errors wrap ErrParseLine naming the line of input that failed. Malformed
+// lines wrap ErrParse and conversion errors are causes of type
+// *ConversionError whose Path is the section and the key, i.e.
+// "server.port". If out is not a non-nil pointer to a struct
+// ErrInvalidParam is returned.
+func (o INIOptions) LoadINI(r io.Reader, out interface{}) error {
+	pv := reflect.ValueOf(out)
+	if r == nil || pv.Kind() != reflect.Ptr || pv.IsNil() || pv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidParam
+	}
+	ec := &errorCollector{collect: o.CollectErrors}
+	entries, err := o.parseINI(r, ec)
+	if err != nil {
+		return err
+	}
+	if len(ec.errs) > 0 && !o.CollectErrors {
+		return ec.result()
+	}
+	for _, entry := range entries {
+		if err := o.loadEntry(pv.Elem(), entry); err != nil {
+			if ec.add(err) {
+				break
+			}
+		}
+	}
+	return ec.result()
+}
+
+// iniValue is a value of a key of an INI file.
+type iniValue struct {
+	// text is the unquoted value.
+	text string
+	// line is the number of the line the value was read from.
+	line int
+}
+
+// iniEntry is a key of a section of an INI file and its' values.
+type iniEntry struct {
+	section, key string
+	values       []iniValue
+}
+
+// parseINI parses an INI file from r into entries in order of first
+// occurence, with values of repeated keys merged. Malformed lines are
+// added to ec. Returns an error if r fails to read.
+func (o INIOptions) parseINI(r io.Reader, ec *errorCollector) ([]*iniEntry, error) {
+	var (
+		entries []*iniEntry
+		byKey   = make(map[string]*iniEntry)
+		section string
+		line    string
+		start   int
+		n       int
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		n++
+		text := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			start = n
+			if text == "" || text[0] == ';' || text[0] == '#' {
+				continue
+			}
+		}
+		if strings.HasSuffix(text, "\\") {
+			line += text[:len(text)-1]
+			continue
+		}
+		line += text
+		text, line = line, ""
+		if text[0] == '[' {
+			if text[len(text)-1] != ']' || strings.TrimSpace(text[1:len(text)-1]) == "" {
+				if ec.add(ErrParseLine.WrapCauseArgs(ErrParse.Wrap("malformed section"), start)) {
+					return nil, nil
+				}
+				continue
+			}
+			section = strings.TrimSpace(text[1 : len(text)-1])
+			continue
+		}
+		i := strings.IndexAny(text, "=:")
+		if i <= 0 {
+			if ec.add(ErrParseLine.WrapCauseArgs(ErrParse.Wrap("malformed key"), start)) {
+				return nil, nil
+			}
+			continue
+		}
+		key, val := strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+		if len(val) > 1 && val[0] == '"' && val[len(val)-1] == '"' {
+			unquoted, err := strconv.Unquote(val)
+			if err != nil {
+				if ec.add(ErrParseLine.WrapCauseArgs(ErrParse.WrapCause("malformed value", err), start)) {
+					return nil, nil
+				}
+				continue
+			}
+			val = unquoted
+		}
+		entry, ok := byKey[section+"\x00"+key]
+		if !ok {
+			entry = &iniEntry{section: section, key: key}
+			byKey[section+"\x00"+key] = entry
+			entries = append(entries, entry)
+		}
+		entry.values = append(entry.values, iniValue{val, start})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, ErrParse.WrapCause("cannot read input", err)
+	}
+	if line != "" {
+		ec.add(ErrParseLine.WrapCauseArgs(ErrParse.Wrap("unterminated line continuation"), start))
+	}
+	return entries, nil
+}
+
+// loadEntry loads entry into struct v.
+func (o INIOptions) loadEntry(v reflect.Value, entry *iniEntry) error {
+	vo := o.valuesOptions()
+	path := entry.key
+	if entry.section != "" {
+		path = entry.section + "." + entry.key
+		for _, name := range strings.Split(entry.section, ".") {
+			if v = indirectAlloc(v); v.Kind() != reflect.Struct {
+				return nil
+			}
+			field, ok := vo.valuesField(v.Type(), strings.TrimSpace(name))
+			if !ok {
+				return nil
+			}
+			if v, ok = fieldByIndexAlloc(v, field.Index, o.AllowUnexported); !ok {
+				return nil
+			}
+		}
+		v = indirectAlloc(v)
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		field, ok := vo.valuesField(v.Type(), entry.key)
+		if !ok {
+			return nil
+		}
+		fv, ok := fieldByIndexAlloc(v, field.Index, o.AllowUnexported)
+		if !ok || !fv.CanSet() {
+			return nil
+		}
+		return o.loadValues(fv, path, entry.values)
+	case reflect.Map:
+		if !v.CanSet() {
+			return nil
+		}
+		key := reflect.New(v.Type().Key()).Elem()
+		if err := (ConvertOptions{}).StringToValue(entry.key, key); err != nil {
+			return ErrParseLine.WrapCauseArgs(conversionError(path, entry.key, key.Type(), err), entry.values[0].line)
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := o.loadValues(elem, path, entry.values); err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		v.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+// loadValues sets settable v to values of a key at path.
+func (o INIOptions) loadValues(v reflect.Value, path string, values []iniValue) error {
+	co := ConvertOptions{CollectErrors: o.CollectErrors}
+	if len(values) > 1 && v.Kind() == reflect.Ptr && repeated(v.Type().Elem()) {
+		v = indirectAlloc(v)
+	}
+	if len(values) == 1 || !repeated(v.Type()) {
+		val := values[len(values)-1]
+		if err := stringToValue(co, val.text, v); err != nil {
+			return ErrParseLine.WrapCauseArgs(conversionError(path, val.text, v.Type(), err), val.line)
+		}
+		return nil
+	}
+	n := len(values)
+	var elems reflect.Value
+	if v.Kind() == reflect.Slice {
+		elems = reflect.MakeSlice(v.Type(), n, n)
+	} else {
+		elems = reflect.New(v.Type()).Elem()
+		if n > v.Len() {
+			n = v.Len()
+		}
+	}
+	ec := &errorCollector{collect: o.CollectErrors}
+	for i := 0; i < n; i++ {
+		val := values[i]
+		if err := stringToValue(co, val.text, elems.Index(i)); err != nil {
+			err = conversionError(fmt.Sprintf("%s[%d]", path, i), val.text, v.Type().Elem(), err)
+			if ec.add(ErrParseLine.WrapCauseArgs(err, val.line)) {
+				break
+			}
+		}
+	}
+	if err := ec.result(); err != nil {
+		return err
+	}
+	v.Set(elems)
+	return nil
+}
+
+// SaveINI saves a struct in, or a struct pointed to by in, to w as an INI
+// file using zero INIOptions. See INIOptions.SaveINI.
+func SaveINI(w io.Writer, in interface{}) error {
+	return INIOptions{}.SaveINI(w, in)
+}
+
+// SaveINI saves a struct in, or a struct pointed to by in, to w as an INI
+// file that LoadINI loads back to an equal value. Values are formatted
+// using ValueToString.
+//
+// Fields of nested structs and maps are saved as sections, after keys of
+// the struct that holds them. Structs implementing TextMarshaler are saved
+// as values. Elements of slices and arrays are saved as repeated keys,
+// except slices of bytes or runes which are saved as text.
+// Values that would not load back as they are, i.e. with leading spaces,
+// are quoted. Nil pointers and interfaces are omitted, as are fields whose
+// tag has the "omitempty" option and whose value is deeply zero. Pointer
+// cycles are omitted when revisited.
+//
+// If in is not a struct ErrInvalidParam is returned. If a value cannot be
+// formatted or a key would not load back, i.e. a map key containing an
+// equal sign, the error wraps ErrIncompatibleField naming its path. Errors
+// of w are returned as they are.
+func (o INIOptions) SaveINI(w io.Writer, in interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(in))
+	if w == nil || v.Kind() != reflect.Struct {
+		return ErrInvalidParam
+	}
+	if o.AllowUnexported {
+		v = addressable(v)
+	}
+	bw := bufio.NewWriter(w)
+	if err := o.saveSection(bw, "", v, make(map[visit]bool)); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// saveSection saves struct or map v, or a pointer or interface to one, as
+// section. Visited holds pointers being saved.
+func (o INIOptions) saveSection(w *bufio.Writer, section string, v reflect.Value, visited map[visit]bool) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		ptr := visitOf(v)
+		if visited[ptr] {
+			return nil
+		}
+		visited[ptr] = true
+		defer delete(visited, ptr)
+		return o.saveSection(w, section, v.Elem(), visited)
+	case reflect.Interface:
+		return o.saveSection(w, section, v.Elem(), visited)
+	}
+	if section != "" {
+		fmt.Fprintf(w, "\n[%s]\n", section)
+	}
+	path := func(key string) string {
+		if section == "" {
+			return key
+		}
+		return section + "." + key
+	}
+	if v.Kind() == reflect.Map {
+		for _, key := range sortedKeys(v) {
+			name, err := ValueToString(key)
+			if err != nil {
+				return ErrIncompatibleField.WrapCauseArgs(err, section)
+			}
+			if err := saveKey(w, name, path(name), v.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	type subsection struct {
+		name string
+		v    reflect.Value
+	}
+	var subsections []subsection
+	vo := o.valuesOptions()
+	for _, field := range TypeInfoOf(v.Type()).fieldList(o.StructOptions) {
+		name := vo.fieldKey(field)
+		if name == "" || field.Name == "_" {
+			continue
+		}
+		fv, ok := fieldByIndex(v, field.Index, o.AllowUnexported)
+		if !ok || !fv.CanInterface() {
+			continue
+		}
+		if field.Tags[o.tag()].HasOption("omitempty") && IsZeroDeepValue(fv) {
+			continue
+		}
+		elem := iniIndirect(fv)
+		if !elem.IsValid() {
+			continue
+		}
+		if iniSection(elem.Type()) {
+			subsections = append(subsections, subsection{name, fv})
+			continue
+		}
+		if err := saveKey(w, name, path(name), elem); err != nil {
+			return err
+		}
+	}
+	for _, sub := range subsections {
+		if err := o.saveSection(w, path(sub.name), sub.v, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iniIndirect returns v with pointers and interfaces dereferenced up to a
+// value implementing TextMarshaler, or an invalid value if v is nil.
+func iniIndirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		if TypeInfoOf(v.Type()).Implements&IfaceTextMarshaler != 0 {
+			return v
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// saveKey saves v under key at path, elements of slices and arrays as
+// repeated keys.
+func saveKey(w *bufio.Writer, key, path string, v reflect.Value) error {
+	if !iniKey(key) {
+		return ErrIncompatibleField.WrapCauseArgs(ErrUnsupported.Wrap("malformed key"), path)
+	}
+	values := []reflect.Value{v}
+	if repeated(v.Type()) {
+		values = values[:0]
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, v.Index(i))
+		}
+	}
+	for _, v := range values {
+		s, err := valueToText(v)
+		if err != nil {
+			return ErrIncompatibleField.WrapCauseArgs(err, path)
+		}
+		if s != strings.TrimSpace(s) || strings.ContainsAny(s, "\r\n") || strings.HasPrefix(s, "\"") || strings.HasSuffix(s, "\\") {
+			s = strconv.Quote(s)
+		}
+		if s == "" {
+			fmt.Fprintf(w, "%s =\n", key)
+			continue
+		}
+		fmt.Fprintf(w, "%s = %s\n", key, s)
+	}
+	return nil
+}
+
+// iniKey returns true if key loads back as it is, i.e. is not empty, has
+// no surrounding spaces, separators or line breaks, does not start a
+// comment or a section and does not end with a line continuation.
+func iniKey(key string) bool {
+	if key == "" || key != strings.TrimSpace(key) || strings.ContainsAny(key, "=:\r\n") {
+		return false
+	}
+	return !strings.ContainsAny(key[:1], ";#[") && !strings.HasSuffix(key, "\\")
+}
+
+// iniSection returns true if values of type t are saved as sections.
+func iniSection(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		return TypeInfoOf(t).Implements&IfaceTextMarshaler == 0
+	case reflect.Map:
+		return true
+	}
+	return false
+}
+
+// valuesOptions returns ValuesOptions matching fields as o does.
+func (o INIOptions) valuesOptions() ValuesOptions {
+	return ValuesOptions{StructOptions: o.StructOptions, Tag: o.tag(), CaseInsensitive: o.CaseInsensitive}
+}
+
+// tag returns the struct tag key naming fields.
+func (o INIOptions) tag() string {
+	if o.Tag == "" {
+		return "ini"
+	}
+	return o.Tag
+}
+
+// indirectAlloc returns v with pointers dereferenced, allocating nil
+// pointers if settable.
+func indirectAlloc(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if !v.CanSet() {
+				return v
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
@@ -0,0 +1,242 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type INITLS struct {
+	Cert string `ini:"cert"`
+	Key  string `ini:"key,omitempty"`
+}
+
+type INIServer struct {
+	Port        uint16   `ini:"port"`
+	MOTD        string   `ini:"motd"`
+	Tags        []string `ini:"tags"`
+	Description string   `ini:"description"`
+	TLS         *INITLS  `ini:"tls"`
+}
+
+type INIConfig struct {
+	Name    string            `ini:"name"`
+	Hosts   []string          `ini:"hosts"`
+	Ports   [2]int            `ini:"ports"`
+	Timeout time.Duration     `ini:"timeout,omitempty"`
+	Since   time.Time         `ini:"since"`
+	Server  INIServer         `ini:"server"`
+	Labels  map[string]int    `ini:"labels"`
+	Extra   map[string]string `ini:"extra,omitempty"`
+	Skip    string            `ini:"-"`
+}
+
+const iniTestFile = `; Comment
+# Another comment
+name = app
+hosts = a,b
+ports = 1
+ports = 2
+since = 2020-01-02T03:04:05Z
+Skip = x
+unknown = x
+
+[server]
+port: 8080
+motd = "  quoted  "
+tags = a
+tags = b
+description = long values continue \
+              on next lines
+name = ignored
+
+[server.tls]
+cert = cert.pem
+
+[labels]
+x = 1
+y = 2
+
+[unknown]
+x = 1
+`
+
+func TestLoadINI(t *testing.T) {
+	config := INIConfig{Skip: "keep"}
+	if err := LoadINI(strings.NewReader(iniTestFile), &config); err != nil {
+		t.Fatal(err)
+	}
+	want := INIConfig{
+		Name:  "app",
+		Hosts: []string{"a", "b"},
+		Ports: [2]int{1, 2},
+		Since: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Server: INIServer{
+			Port:        8080,
+			MOTD:        "  quoted  ",
+			Tags:        []string{"a", "b"},
+			Description: "long values continue on next lines",
+			TLS:         &INITLS{Cert: "cert.pem"},
+		},
+		Labels: map[string]int{"x": 1, "y": 2},
+		Skip:   "keep",
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Fatalf("LoadINI failed: %#v", config)
+	}
+	var server INIServer
+	if err := (INIOptions{CaseInsensitive: true}).LoadINI(strings.NewReader("PORT=1\n[TLS]\nCERT=x"), &server); err != nil {
+		t.Fatal(err)
+	}
+	if server.Port != 1 || server.TLS == nil || server.TLS.Cert != "x" {
+		t.Fatalf("LoadINI(CaseInsensitive) failed: %#v", server)
+	}
+	var text struct {
+		Data []byte
+	}
+	if err := LoadINI(strings.NewReader("Data = hello"), &text); err != nil || string(text.Data) != "hello" {
+		t.Fatalf("LoadINI(bytes) failed: %q %v", text.Data, err)
+	}
+	buf := &bytes.Buffer{}
+	if err := SaveINI(buf, text); err != nil || buf.String() != "Data = hello\n" {
+		t.Fatalf("SaveINI(bytes) failed: %q %v", buf, err)
+	}
+	if err := LoadINI(strings.NewReader(""), config); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("LoadINI failed, expected invalid param error")
+	}
+	if err := LoadINI(nil, &config); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("LoadINI failed, expected invalid param error")
+	}
+}
+
+func TestLoadINIErrors(t *testing.T) {
+	tests := []struct {
+		in      string
+		message string
+		path    string
+	}{
+		{"name = x\n[server\nport = 1", "line 2", ""},
+		{"; comment\n\nname", "line 3", ""},
+		{"= x", "line 1", ""},
+		{"name = \"x\"y\"", "line 1", ""},
+		{"name = \"x\\q\"", "line 1", ""},
+		{"name = x \\", "line 1", ""},
+		{"[server]\nport = 1\nport = x", "line 3", "server.port"},
+		{"hosts = a\n[server]\ntags = a\n\ntags = \\\n  b\nport = 70000", "line 7", "server.port"},
+		{"ports = 1\nports = x", "line 2", "ports[1]"},
+		{"[labels]\nx = y", "line 2", "labels.x"},
+	}
+	for i, test := range tests {
+		var config INIConfig
+		err := LoadINI(strings.NewReader(test.in), &config)
+		if !errors.Is(err, ErrParseLine) || !errors.Is(err, ErrParse) || !strings.Contains(err.Error(), test.message) {
+			t.Fatalf("LoadINI failed at %d, expected parse error at %s: %v", i, test.message, err)
+		}
+		if test.path == "" {
+			continue
+		}
+		if !errors.Is(err, ErrConvert) || !strings.Contains(err.Error(), "at '"+test.path+"'") {
+			t.Fatalf("LoadINI failed at %d, expected conversion error at %s: %v", i, test.path, err)
+		}
+	}
+	var config INIConfig
+	err := INIOptions{CollectErrors: true}.LoadINI(strings.NewReader("name\nports = x\nports = y\n[server]\nport = z"), &config)
+	var me MultiError
+	if !errors.As(err, &me) || len(me) != 4 {
+		t.Fatalf("LoadINI(CollectErrors) failed: %v", err)
+	}
+}
+
+func TestSaveINI(t *testing.T) {
+	config := INIConfig{
+		Name:    "app",
+		Hosts:   []string{"a", "b"},
+		Ports:   [2]int{1, 2},
+		Timeout: time.Second,
+		Since:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Server: INIServer{
+			Port: 8080,
+			MOTD: "  quoted \"  \n",
+			TLS:  &INITLS{Cert: "cert.pem"},
+		},
+		Labels: map[string]int{"y": 2, "x": 1},
+		Skip:   "x",
+	}
+	buf := &bytes.Buffer{}
+	if err := SaveINI(buf, &config); err != nil {
+		t.Fatal(err)
+	}
+	want := `name = app
+hosts = a
+hosts = b
+ports = 1
+ports = 2
+timeout = 1000000000
+since = 2020-01-02T03:04:05Z
+
+[server]
+port = 8080
+motd = "  quoted \"  \n"
+description =
+
+[server.tls]
+cert = cert.pem
+
+[labels]
+x = 1
+y = 2
+`
+	if buf.String() != want {
+		t.Fatalf("SaveINI failed:\n%s", buf)
+	}
+	var loaded INIConfig
+	if err := LoadINI(buf, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	config.Skip = ""
+	if !reflect.DeepEqual(loaded, config) {
+		t.Fatalf("SaveINI failed to round trip: %#v", loaded)
+	}
+	if err := SaveINI(buf, 1); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("SaveINI failed, expected invalid param error")
+	}
+	type Invalid struct {
+		Ch chan int
+	}
+	if err := SaveINI(buf, Invalid{make(chan int)}); !errors.Is(err, ErrIncompatibleField) {
+		t.Fatal("SaveINI failed, expected incompatible field error")
+	}
+	for _, key := range []string{"a=b", "k:", "", " k", "#k", "[k", "k\\", "a\nb"} {
+		extra := INIConfig{Extra: map[string]string{key: "c"}}
+		if err := SaveINI(buf, extra); !errors.Is(err, ErrIncompatibleField) || !errors.Is(err, ErrUnsupported) {
+			t.Fatalf("SaveINI failed, expected incompatible field error for key %q: %v", key, err)
+		}
+	}
+}
+
+func FuzzLoadINI(f *testing.F) {
+	f.Add(iniTestFile, uint8(0))
+	f.Add("name\n[server\nports = x\\\n", uint8(3))
+	f.Fuzz(func(t *testing.T, s string, flags uint8) {
+		o := INIOptions{CaseInsensitive: flags&1 != 0, CollectErrors: flags&2 != 0}
+		var config INIConfig
+		if o.LoadINI(strings.NewReader(s), &config) != nil {
+			return
+		}
+		buf := &bytes.Buffer{}
+		if err := o.SaveINI(buf, config); err != nil {
+			t.Fatalf("SaveINI failed on loaded value: %v", err)
+		}
+		var loaded INIConfig
+		if err := o.LoadINI(buf, &loaded); err != nil {
+			t.Fatalf("LoadINI failed on saved value: %v\n%s", err, buf)
+		}
+	})
+}
@@ -0,0 +1,281 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import "reflect"
+
+// MapRule is a rule that modifies how a MapPlan is compiled.
+type MapRule func(*mapRules)
+
+// mapRules are collected MapRules.
+type mapRules struct {
+	// renames map destination field names to source field names.
+	renames map[string]string
+	// ignored are ignored destination field names.
+	ignored map[string]bool
+	// converters map destination field names to converter funcs.
+	converters map[string]interface{}
+	// defaults map destination field names to default values.
+	defaults map[string]interface{}
+}
+
+// Rename maps the source field named src to the destination field named dst.
+func Rename(src, dst string) MapRule {
+	return func(r *mapRules) { r.renames[dst] = src }
+}
+
+// Ignore excludes destination fields by name from mapping.
+func Ignore(dst ...string) MapRule {
+	return func(r *mapRules) {
+		for _, name := range dst {
+			r.ignored[name] = true
+		}
+	}
+}
+
+// Converter sets a func that converts the source value to the value of the
+// destination field named dst. Fn must be a func of the form func(S) D or
+// func(S) (D, error) where source field type is assignable to S and D is
+// assignable to destination field type. Compile fails if the destination
+// field has no source field.
+func Converter(dst string, fn interface{}) MapRule {
+	return func(r *mapRules) { r.converters[dst] = fn }
+}
+
+// Default sets a value set to the destination field named dst if the source
+// field is zero or missing. Value must be assignable or convertible to the
+// destination field type. Value is deep copied on every assignment so
+// destinations never share maps, slices or pointers of value.
+func Default(dst string, value interface{}) MapRule {
+	return func(r *mapRules) { r.defaults[dst] = value }
+}
+
+// Mapper compiles mapping plans between struct types.
+type Mapper struct {
+	// StructOptions define how struct fields are treated.
+	StructOptions
+	// Strict, if true, makes Compile fail if a destination field is left
+	// without a source field or a default value and is not ignored.
+	Strict bool
+}
+
+// MapPlan is a compiled mapping from a source to a destination struct type.
+// It is safe for concurrent use.
+type MapPlan struct {
+	src        reflect.Type
+	dst        reflect.Type
+	steps      []mapStep
+	unexported bool
+}
+
+// mapStep maps a single destination field.
+type mapStep struct {
+	// name is the destination field name.
+	name string
+	// src is the source field index, nil if destination has no source.
+	src []int
+	// dst is the destination field index.
+	dst []int
+	// conv converts a source value to a destination value.
+	conv func(reflect.Value) (reflect.Value, error)
+	// def is the default value, invalid if none.
+	def reflect.Value
+}
+
+// Compile compiles a mapping plan from struct type src to struct type dst
+// using rules. Destination fields are mapped from source fields of the same
+// name unless renamed and values are assigned, converted or passed through
+// converters. Destination fields without a source are left untouched, unless
+// defaulted.
+//
+// An error is returned if a rule names a field that does not exist, a
+// converter or default value does not match field types or a source value
+// is neither assignable nor convertible to a destination field.
+func (m Mapper) Compile(src, dst reflect.Type, rules ...MapRule) (*MapPlan, error) {
+	if src == nil || dst == nil || src.Kind() != reflect.Struct || dst.Kind() != reflect.Struct {
+		return nil, ErrInvalidParam
+	}
+	r := &mapRules{
+		renames:    make(map[string]string),
+		ignored:    make(map[string]bool),
+		converters: make(map[string]interface{}),
+		defaults:   make(map[string]interface{}),
+	}
+	for _, rule := range rules {
+		if rule == nil {
+			return nil, ErrInvalidParam
+		}
+		rule(r)
+	}
+	srcti, dstti := TypeInfoOf(src), TypeInfoOf(dst)
+	if err := m.checkRules(r, srcti, dstti); err != nil {
+		return nil, err
+	}
+	plan := &MapPlan{src: src, dst: dst, unexported: m.AllowUnexported}
+	for _, dstfield := range dstti.fieldList(m.StructOptions) {
+		if r.ignored[dstfield.Name] {
+			continue
+		}
+		step := mapStep{name: dstfield.Name, dst: dstfield.Index}
+		if def, ok := r.defaults[dstfield.Name]; ok {
+			dv, err := assignableValue(reflect.ValueOf(def), dstfield.Type)
+			if err != nil {
+				return nil, ErrIncompatibleField.WrapCauseArgs(err, dstfield.Name)
+			}
+			step.def = dv
+		}
+		name := dstfield.Name
+		if renamed, ok := r.renames[name]; ok {
+			name = renamed
+		}
+		srcfield, ok := srcti.field(name, m.StructOptions)
+		if !ok {
+			if _, ok := r.converters[dstfield.Name]; ok {
+				return nil, ErrIncompatibleField.WrapCauseArgs(ErrFieldNotFound.WrapArgs(name), dstfield.Name)
+			}
+			if !step.def.IsValid() {
+				if m.Strict {
+					return nil, ErrIncompatibleField.WrapCauseArgs(ErrFieldNotFound.WrapArgs(name), dstfield.Name)
+				}
+				continue
+			}
+		} else {
+			step.src = srcfield.Index
+			conv, err := compileConverter(srcfield.Type, dstfield.Type, r.converters[dstfield.Name])
+			if err != nil {
+				return nil, ErrIncompatibleField.WrapCauseArgs(err, dstfield.Name)
+			}
+			step.conv = conv
+		}
+		plan.steps = append(plan.steps, step)
+	}
+	return plan, nil
+}
+
+// checkRules checks that rules r name existing fields.
+func (m Mapper) checkRules(r *mapRules, srcti, dstti *TypeInfo) error {
+	for dst, src := range r.renames {
+		if _, ok := dstti.field(dst, m.StructOptions); !ok {
+			return ErrFieldNotFound.WrapArgs(dst)
+		}
+		if _, ok := srcti.field(src, m.StructOptions); !ok {
+			return ErrFieldNotFound.WrapArgs(src)
+		}
+	}
+	for _, names := range []map[string]interface{}{r.converters, r.defaults} {
+		for dst := range names {
+			if _, ok := dstti.field(dst, m.StructOptions); !ok {
+				return ErrFieldNotFound.WrapArgs(dst)
+			}
+		}
+	}
+	for dst := range r.ignored {
+		if _, ok := dstti.field(dst, m.StructOptions); !ok {
+			return ErrFieldNotFound.WrapArgs(dst)
+		}
+	}
+	return nil
+}
+
+// compileConverter returns a func that converts values of type src to type
+// dst, optionally through a converter func fn.
+func compileConverter(src, dst reflect.Type, fn interface{}) (func(reflect.Value) (reflect.Value, error), error) {
+	if fn == nil {
+		if src.AssignableTo(dst) {
+			return nil, nil
+		}
+		if convertible(src, dst) {
+			return func(v reflect.Value) (reflect.Value, error) {
+				if cv, ok := convertValue(v, dst); ok {
+					return cv, nil
+				}
+				return reflect.Value{}, ErrConvert.WrapArgs(src, dst)
+			}, nil
+		}
+		return nil, ErrConvert.WrapArgs(src, dst)
+	}
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func || fv.IsNil() || ft.NumIn() != 1 || ft.IsVariadic() ||
+		ft.NumOut() < 1 || ft.NumOut() > 2 ||
+		(ft.NumOut() == 2 && ft.Out(1) != errorType) {
+		return nil, ErrInvalidParam
+	}
+	if !src.AssignableTo(ft.In(0)) {
+		return nil, ErrConvert.WrapArgs(src, ft.In(0))
+	}
+	if !ft.Out(0).AssignableTo(dst) {
+		return nil, ErrConvert.WrapArgs(ft.Out(0), dst)
+	}
+	return func(v reflect.Value) (reflect.Value, error) {
+		out := fv.Call([]reflect.Value{v})
+		if len(out) == 2 && !out[1].IsNil() {
+			return reflect.Value{}, out[1].Interface().(error)
+		}
+		return out[0], nil
+	}, nil
+}
+
+// assignableValue returns v assignable to type t, converting it if required.
+func assignableValue(v reflect.Value, t reflect.Type) (reflect.Value, error) {
+	if !v.IsValid() {
+		return reflect.Zero(t), nil
+	}
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if convertible(v.Type(), t) {
+		if cv, ok := convertValue(v, t); ok {
+			return cv, nil
+		}
+	}
+	return reflect.Value{}, ErrConvert.WrapArgs(v.Type(), t)
+}
+
+// Map maps src, a struct or a pointer to a struct of plan source type, to
+// dst which must be a pointer to a struct of plan destination type.
+func (p *MapPlan) Map(src, dst interface{}) error {
+	dstv := reflect.ValueOf(dst)
+	if dstv.Kind() != reflect.Ptr || dstv.IsNil() {
+		return ErrInvalidParam
+	}
+	return p.MapValue(reflect.Indirect(reflect.ValueOf(src)), dstv.Elem())
+}
+
+// MapValue maps src struct value of plan source type to a settable dst
+// struct value of plan destination type.
+func (p *MapPlan) MapValue(src, dst reflect.Value) error {
+	if !src.IsValid() || src.Type() != p.src || !dst.IsValid() || dst.Type() != p.dst || !dst.CanSet() {
+		return ErrInvalidParam
+	}
+	if p.unexported {
+		src = addressable(src)
+	}
+	for _, step := range p.steps {
+		var val reflect.Value
+		if step.src != nil {
+			val, _ = fieldByIndex(src, step.src, p.unexported)
+			if val.IsValid() && !val.CanInterface() {
+				continue
+			}
+		}
+		if step.def.IsValid() && (!val.IsValid() || val.IsZero()) {
+			val = CloneValue(step.def)
+		} else if !val.IsValid() {
+			continue
+		} else if step.conv != nil {
+			var err error
+			if val, err = step.conv(val); err != nil {
+				return ErrIncompatibleField.WrapCauseArgs(err, step.name)
+			}
+		}
+		tgt, ok := fieldByIndexAlloc(dst, step.dst, p.unexported)
+		if !ok || !tgt.CanSet() {
+			continue
+		}
+		tgt.Set(val)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type MapperSrc struct {
+	ID       int
+	FullName string
+	Age      int32
+	Score    string
+	Secret   string
+	Country  string
+}
+
+type MapperDst struct {
+	ID      int
+	Name    string
+	Age     int64
+	Score   float64
+	Secret  string
+	Country string
+	Role    string
+}
+
+func TestMapper(t *testing.T) {
+	plan, err := Mapper{}.Compile(
+		reflect.TypeOf(MapperSrc{}),
+		reflect.TypeOf(MapperDst{}),
+		Rename("FullName", "Name"),
+		Converter("Score", func(s string) (float64, error) {
+			return strconv.ParseFloat(s, 64)
+		}),
+		Ignore("Secret"),
+		Default("Country", "HR"),
+		Default("Role", "user"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &MapperSrc{42, "Foo Bar", 69, "3.14", "hunter2", ""}
+	dst := &MapperDst{}
+	if err := plan.Map(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	want := MapperDst{42, "Foo Bar", 69, 3.14, "", "HR", "user"}
+	if *dst != want {
+		t.Fatalf("Mapper failed: %#v", dst)
+	}
+	src.Score = "NaN?"
+	if err := plan.Map(src, dst); !errors.Is(err, ErrIncompatibleField) {
+		t.Fatal("Mapper failed, expected converter error")
+	}
+	if err := plan.Map(src, MapperDst{}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("Mapper failed, expected invalid param error")
+	}
+	if err := plan.Map(&MapperDst{}, dst); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("Mapper failed, expected invalid param error")
+	}
+}
+
+func TestMapperDefaultCopy(t *testing.T) {
+
+	type (
+		Src struct{}
+
+		Dst struct {
+			Labels map[string]int
+		}
+	)
+
+	labels := map[string]int{"a": 1}
+	plan, err := Mapper{}.Compile(reflect.TypeOf(Src{}), reflect.TypeOf(Dst{}), Default("Labels", labels))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a, b Dst
+	if err := plan.Map(Src{}, &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Map(Src{}, &b); err != nil {
+		t.Fatal(err)
+	}
+	a.Labels["b"] = 2
+	if len(b.Labels) != 1 || len(labels) != 1 {
+		t.Fatal("Mapper failed, default value shared between destinations")
+	}
+}
+
+func TestMapperCompileErrors(t *testing.T) {
+	src, dst := reflect.TypeOf(MapperSrc{}), reflect.TypeOf(MapperDst{})
+	// Score string is not convertible to float64.
+	if _, err := (Mapper{}).Compile(src, dst); !errors.Is(err, ErrIncompatibleField) {
+		t.Fatal("Compile failed, expected incompatible field error")
+	}
+	if _, err := (Mapper{}).Compile(src, dst, Ignore("Score", "Nope")); !errors.Is(err, ErrFieldNotFound) {
+		t.Fatal("Compile failed, expected field not found error")
+	}
+	if _, err := (Mapper{}).Compile(src, dst, Rename("Nope", "Name")); !errors.Is(err, ErrFieldNotFound) {
+		t.Fatal("Compile failed, expected field not found error")
+	}
+	if _, err := (Mapper{}).Compile(src, dst, Converter("Score", func(i int) float64 { return 0 })); !errors.Is(err, ErrConvert) {
+		t.Fatal("Compile failed, expected convert error")
+	}
+	if _, err := (Mapper{}).Compile(src, dst, Ignore("Score"), Converter("Role", func(s string) string { return s })); !errors.Is(err, ErrFieldNotFound) {
+		t.Fatal("Compile failed, expected field not found error")
+	}
+	if _, err := (Mapper{}).Compile(src, dst, Converter("Score", 42)); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("Compile failed, expected invalid param error")
+	}
+	if _, err := (Mapper{}).Compile(src, dst, Ignore("Score"), Default("Role", 42)); !errors.Is(err, ErrConvert) {
+		t.Fatal("Compile failed, expected convert error")
+	}
+	if _, err := (Mapper{Strict: true}).Compile(src, dst, Ignore("Score")); !errors.Is(err, ErrFieldNotFound) {
+		t.Fatal("Compile failed, expected field not found error")
+	}
+	if _, err := (Mapper{}).Compile(src, reflect.TypeOf(0)); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("Compile failed, expected invalid param error")
+	}
+}
+
+func TestMapperFlatten(t *testing.T) {
+
+	type Dst struct {
+		ID    int
+		Deep  bool
+		Email string
+	}
+
+	plan, err := Mapper{StructOptions: StructOptions{Flatten: true}}.Compile(
+		reflect.TypeOf(Dst{}), reflect.TypeOf(EmbedTest{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := &EmbedTest{}
+	if err := plan.Map(Dst{42, true, "foo@bar.baz"}, dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.EmbedBase == nil || dst.ID != 42 || !dst.Deep || dst.Email != "foo@bar.baz" {
+		t.Fatal("Mapper(Flatten) failed")
+	}
+}
+
+func TestMapperAllowUnexported(t *testing.T) {
+
+	type Src struct {
+		count int
+	}
+
+	type Dst struct {
+		count int64
+	}
+
+	src, dst := reflect.TypeOf(Src{}), reflect.TypeOf(Dst{})
+	for _, allow := range []bool{false, true} {
+		plan, err := Mapper{StructOptions: StructOptions{AllowUnexported: allow}}.Compile(src, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := &Dst{}
+		if err := plan.Map(Src{42}, out); err != nil {
+			t.Fatal(err)
+		}
+		if (out.count == 42) != allow {
+			t.Fatalf("Mapper(AllowUnexported: %t) failed", allow)
+		}
+	}
+}
+
+type mapperBenchA struct {
+	Field0 string
+	Field1 int
+	Field2 uint
+	Field3 float32
+	Field4 float64
+	Field5 complex64
+	Field6 complex128
+	Field7 rune
+	Field8 bool
+	Field9 []byte
+}
+
+type mapperBenchB struct {
+	Field0 string
+	Field1 int
+	Field2 uint
+	Field3 float32
+	Field4 float64
+	Field5 complex64
+	Field6 complex128
+	Field7 rune
+	Field8 bool
+	Field9 []byte
+}
+
+func BenchmarkMapPlan(b *testing.B) {
+	b.StopTimer()
+	plan, err := Mapper{}.Compile(reflect.TypeOf(mapperBenchA{}), reflect.TypeOf(mapperBenchB{}))
+	if err != nil {
+		b.Fatal(err)
+	}
+	testA := &mapperBenchA{"one", 2, 3, 4.0, 5.0, 6.0i, 7.0i, '8', true, []byte("nein")}
+	testB := &mapperBenchB{}
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		plan.Map(testA, testB)
+	}
+}
+
+func BenchmarkMapPlanLazyStructCopy(b *testing.B) {
+	b.StopTimer()
+	testA := &mapperBenchA{"one", 2, 3, 4.0, 5.0, 6.0i, 7.0i, '8', true, []byte("nein")}
+	testB := &mapperBenchB{}
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		LazyStructCopy(testA, testB)
+	}
+}
@@ -0,0 +1,679 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MapOptions define how DecodeMap and EncodeMap map keys of
+// map[string]interface{} trees to struct fields.
+type MapOptions struct {
+	// StructOptions define how struct fields are treated.
+	StructOptions
+	// Tag is the struct tag key whose name overrides the key of a field.
+	// If empty, "map" is used. Fields tagged "-" are excluded. Fields of
+	// embedded structs tagged with the "squash" option, i.e.
+	// `map:",squash"`, are mapped as if declared by the embedding struct.
+	Tag string
+	// WeaklyTyped, if true, makes DecodeMap convert values to fields of
+	// other types as described by ConvertValue, i.e. "8080" to an int.
+	//
+	// If false, values must be assignable to fields or convertible by Go
+	// to fields of the same kind, numbers convert to numbers of other
+	// kinds if representable and strings convert to TextUnmarshaler
+	// fields.
+	WeaklyTyped bool
+	// CaseInsensitive, if true, matches keys to field names ignoring case
+	// if no name matches exactly.
+	CaseInsensitive bool
+	// ErrorUnused, if true, makes DecodeMap return an error for every key
+	// that sets no field.
+	ErrorUnused bool
+	// CollectErrors, if true, makes DecodeMap decode all keys and return
+	// all errors as a MultiError instead of returning the first error.
+	CollectErrors bool
+	// Metadata, if not nil, is filled by DecodeMap with paths of keys that
+	// were decoded.
+	Metadata *MapMetadata
+}
+
+// MapMetadata describes keys decoded by DecodeMap. Paths of keys are keys
+// of nested maps joined by dots, with indexes of slices and keys of maps
+// that do not decode to structs in brackets, i.e. "servers[0].port".
+type MapMetadata struct {
+	// Keys are paths of keys that set a struct field, in decoding order.
+	Keys []string
+	// Unused are paths of keys that set no struct field, in decoding
+	// order.
+	Unused []string
+}
+
+// DecodeMap decodes in, i.e. a map decoded from JSON or YAML, into a
+// value pointed to by out using options opts.
+//
+// Maps decode to structs by setting fields named by keys, as named by
+// their tag or by their name; keys that name no field are unused. Maps
+// decode to maps by decoding keys and elements, merged with existing
+// entries. Slices and arrays decode to slices and arrays element by
+// element. Maps and slices decoded to interfaces are copied, not shared
+// with in. Nil pointers are allocated. Other values are decoded as
+// described by MapOptions.WeaklyTyped. Nil values set zero values.
+//
+// Keys are decoded in sorted order and fields not named by any key are
+// left unmodified. Decoding errors are returned as *ConversionError whose
+// Path is the path of the key as described by MapMetadata. If
+// opts.ErrorUnused is true unused keys are returned as errors wrapping
+// ErrFieldNotFound. Out may be partially decoded if an error occurs.
+//
+// If out is not a non-nil pointer to a struct or a map ErrInvalidParam is
+// returned.
+func DecodeMap(in map[string]interface{}, out interface{}, opts MapOptions) error {
+	pv := reflect.ValueOf(out)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() {
+		return ErrInvalidParam
+	}
+	v := pv.Elem()
+	if v.Kind() != reflect.Struct && v.Kind() != reflect.Map {
+		return ErrInvalidParam
+	}
+	d := &mapDecoder{
+		MapOptions: opts,
+		co:         ConvertOptions{CollectErrors: opts.CollectErrors},
+		meta:       opts.Metadata,
+		fields:     make(map[reflect.Type][]mapField),
+		visited:    make(map[visit]bool),
+	}
+	if d.meta == nil {
+		d.meta = &MapMetadata{}
+	}
+	ec := &errorCollector{collect: opts.CollectErrors}
+	if err := d.decode(reflect.ValueOf(in), v, ""); err != nil {
+		if ec.add(err) {
+			return ec.result()
+		}
+	}
+	if opts.ErrorUnused {
+		for _, path := range d.meta.Unused {
+			if ec.add(ErrFieldNotFound.WrapArgs(path)) {
+				break
+			}
+		}
+	}
+	return ec.result()
+}
+
+// mapDecoder holds the state of a DecodeMap call.
+type mapDecoder struct {
+	MapOptions
+	// co are options of conversions.
+	co ConvertOptions
+	// meta receives decoded keys.
+	meta *MapMetadata
+	// fields caches mapped fields by struct type.
+	fields map[reflect.Type][]mapField
+	// visited holds references being decoded.
+	visited map[visit]bool
+}
+
+// decode decodes v to settable out at path.
+func (d *mapDecoder) decode(v, out reflect.Value, path string) error {
+	t := out.Type()
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Invalid:
+		out.Set(reflect.Zero(t))
+		return nil
+	case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			out.Set(reflect.Zero(t))
+			return nil
+		}
+	}
+	switch {
+	case t.Kind() == reflect.Interface && (v.Kind() == reflect.Map || v.Kind() == reflect.Slice):
+		// Decode to a copy so out does not share v, merged with the current
+		// map of out if of the same type.
+		elem := reflect.New(v.Type()).Elem()
+		if current := out.Elem(); v.Kind() == reflect.Map && current.IsValid() && current.Type() == v.Type() {
+			elem.Set(current)
+		}
+		if err := d.decode(v, elem, path); err != nil {
+			return err
+		}
+		out.Set(elem)
+		return nil
+	case v.Kind() != reflect.Map && v.Kind() != reflect.Slice && v.Type().AssignableTo(t) && v.CanInterface():
+		out.Set(v)
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		if out.IsNil() {
+			out.Set(reflect.New(t.Elem()))
+		}
+		return d.decode(v, out.Elem(), path)
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.Kind() == reflect.Slice && v.Len() == 0 {
+			break
+		}
+		key := visitOf(v)
+		if d.visited[key] {
+			return conversionError(path, "", nil, convertFailure(v, t, ErrUnsupported.Wrap("reference cycle")))
+		}
+		d.visited[key] = true
+		defer delete(d.visited, key)
+	}
+	if v.Kind() == reflect.Ptr {
+		return d.decode(v.Elem(), out, path)
+	}
+	switch {
+	case t.Kind() == reflect.Struct && v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		return d.decodeStruct(v, out, path)
+	case t.Kind() == reflect.Map && v.Kind() == reflect.Map:
+		return d.decodeMap(v, out, path)
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) &&
+		(v.Kind() == reflect.Slice || v.Kind() == reflect.Array):
+		return d.decodeElems(v, out, path)
+	}
+	return d.decodeValue(v, out, path)
+}
+
+// decodeStruct decodes map v with string keys to struct out at path.
+func (d *mapDecoder) decodeStruct(v, out reflect.Value, path string) error {
+	fields, ok := d.fields[out.Type()]
+	if !ok {
+		fields = d.mapFields(out.Type())
+		d.fields[out.Type()] = fields
+	}
+	ec := &errorCollector{collect: d.CollectErrors}
+	for _, key := range sortedKeys(v) {
+		keyPath := key.String()
+		if path != "" {
+			keyPath = path + "." + keyPath
+		}
+		field, ok := d.lookupField(fields, key.String())
+		if !ok {
+			d.meta.Unused = append(d.meta.Unused, keyPath)
+			continue
+		}
+		fv, ok := fieldByIndexAlloc(out, field.index, d.AllowUnexported)
+		if !ok || !fv.CanSet() {
+			d.meta.Unused = append(d.meta.Unused, keyPath)
+			continue
+		}
+		if err := d.decode(v.MapIndex(key), fv, keyPath); err != nil {
+			if ec.add(err) {
+				break
+			}
+			continue
+		}
+		d.meta.Keys = append(d.meta.Keys, keyPath)
+	}
+	return ec.result()
+}
+
+// decodeMap decodes map v to map out at path, merging with existing
+// entries of out.
+func (d *mapDecoder) decodeMap(v, out reflect.Value, path string) error {
+	t := out.Type()
+	if out.IsNil() {
+		out.Set(reflect.MakeMapWithSize(t, v.Len()))
+	}
+	ec := &errorCollector{collect: d.CollectErrors}
+	for _, key := range sortedKeys(v) {
+		keyPath := path + "[" + inputString(key) + "]"
+		k := reflect.New(t.Key()).Elem()
+		if err := d.decode(key, k, keyPath); err != nil {
+			if ec.add(err) {
+				break
+			}
+			continue
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		if current := out.MapIndex(k); current.IsValid() {
+			elem.Set(current)
+		}
+		if err := d.decode(v.MapIndex(key), elem, keyPath); err != nil {
+			if ec.add(err) {
+				break
+			}
+			continue
+		}
+		out.SetMapIndex(k, elem)
+	}
+	return ec.result()
+}
+
+// decodeElems decodes slice or array v to slice or array out at path
+// element by element.
+func (d *mapDecoder) decodeElems(v, out reflect.Value, path string) error {
+	t := out.Type()
+	elems := reflect.New(t).Elem()
+	if t.Kind() == reflect.Slice {
+		elems.Set(reflect.MakeSlice(t, v.Len(), v.Len()))
+	} else if v.Len() > t.Len() {
+		return conversionError(path, "", nil, convertFailure(v, t, strconv.ErrRange))
+	}
+	ec := &errorCollector{collect: d.CollectErrors}
+	for i := 0; i < v.Len(); i++ {
+		if err := d.decode(v.Index(i), elems.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+			if ec.add(err) {
+				break
+			}
+		}
+	}
+	if err := ec.result(); err != nil {
+		return err
+	}
+	out.Set(elems)
+	return nil
+}
+
+// decodeValue decodes v to out at path as described by
+// MapOptions.WeaklyTyped.
+func (d *mapDecoder) decodeValue(v, out reflect.Value, path string) error {
+	t := out.Type()
+	if d.WeaklyTyped {
+		cv, err := d.co.ConvertValue(v, t)
+		if err != nil {
+			return conversionError(path, "", nil, err)
+		}
+		out.Set(cv)
+		return nil
+	}
+	ti := TypeInfoOf(t)
+	switch {
+	case isNumber(v.Kind()) && isNumber(t.Kind()):
+		if err := convertNumber(v, out); err != nil {
+			return conversionError(path, "", nil, err)
+		}
+		return nil
+	case v.Kind() == reflect.String && (ti.Implements|ti.PtrImplements)&IfaceTextUnmarshaler != 0:
+		if err := d.co.StringToValue(v.String(), out); err != nil {
+			return conversionError(path, v.String(), t, err)
+		}
+		return nil
+	case v.Kind() == t.Kind() && convertible(v.Type(), t) && v.CanInterface():
+		if cv, ok := convertValue(v, t); ok {
+			out.Set(cv)
+			return nil
+		}
+	}
+	return conversionError(path, "", nil, convertFailure(v, t, ErrUnsupported))
+}
+
+// EncodeMap encodes a struct or a map in, or a pointer to one, to a
+// map[string]interface{} tree that DecodeMap decodes back to an equal
+// value, using options opts.
+//
+// Structs and maps encode to map[string]interface{}, with fields keyed as
+// described by MapOptions.Tag and map keys formatted by ValueToString.
+// Slices and arrays encode to []interface{}, except slices of bytes or
+// runes. Pointers and interfaces are dereferenced; nil ones encode to nil
+// and reference cycles are encoded as nil when revisited. Other values and
+// values implementing TextMarshaler are stored as they are. Fields whose
+// tag has the "omitempty" option and whose value is deeply zero are
+// omitted.
+//
+// If in is not a struct or a map ErrInvalidParam is returned. If a map key
+// cannot be formatted the error wraps ErrIncompatibleField naming its
+// path.
+func EncodeMap(in interface{}, opts MapOptions) (map[string]interface{}, error) {
+	v := reflect.Indirect(reflect.ValueOf(in))
+	if v.Kind() != reflect.Struct && v.Kind() != reflect.Map {
+		return nil, ErrInvalidParam
+	}
+	if opts.AllowUnexported {
+		v = addressable(v)
+	}
+	result, err := opts.encode(v, "", make(map[visit]bool))
+	if err != nil {
+		return nil, err
+	}
+	m, _ := result.(map[string]interface{})
+	return m, nil
+}
+
+// encode encodes v at path. Visited holds pointers, maps and slices being
+// encoded.
+func (o MapOptions) encode(v reflect.Value, path string, visited map[visit]bool) (interface{}, error) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+	}
+	if TypeInfoOf(v.Type()).Implements&IfaceTextMarshaler != 0 {
+		return v.Interface(), nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		ptr := visitOf(v)
+		if visited[ptr] {
+			return nil, nil
+		}
+		visited[ptr] = true
+		defer delete(visited, ptr)
+		return o.encode(v.Elem(), path, visited)
+	case reflect.Interface:
+		return o.encode(v.Elem(), path, visited)
+	case reflect.Struct:
+		m := make(map[string]interface{})
+		for _, field := range o.mapFields(v.Type()) {
+			fv, ok := fieldByIndex(v, field.index, o.AllowUnexported)
+			if !ok || !fv.CanInterface() {
+				continue
+			}
+			if field.omitempty && IsZeroDeepValue(fv) {
+				continue
+			}
+			keyPath := field.name
+			if path != "" {
+				keyPath = path + "." + keyPath
+			}
+			val, err := o.encode(fv, keyPath, visited)
+			if err != nil {
+				return nil, err
+			}
+			m[field.name] = val
+		}
+		return m, nil
+	case reflect.Map:
+		key := visitOf(v)
+		if visited[key] {
+			return nil, nil
+		}
+		visited[key] = true
+		defer delete(visited, key)
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := ValueToString(iter.Key())
+			if err != nil {
+				return nil, ErrIncompatibleField.WrapCauseArgs(err, path)
+			}
+			val, err := o.encode(iter.Value(), path+"["+key+"]", visited)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = val
+		}
+		return m, nil
+	case reflect.Slice, reflect.Array:
+		if runesOrBytes(v.Type()) {
+			break
+		}
+		if v.Kind() == reflect.Slice && v.Len() > 0 {
+			key := visitOf(v)
+			if visited[key] {
+				return nil, nil
+			}
+			visited[key] = true
+			defer delete(visited, key)
+		}
+		a := make([]interface{}, v.Len())
+		for i := range a {
+			val, err := o.encode(v.Index(i), fmt.Sprintf("%s[%d]", path, i), visited)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = val
+		}
+		return a, nil
+	}
+	return v.Interface(), nil
+}
+
+// mapField is a struct field mapped to a key.
+type mapField struct {
+	// name is the key of the field.
+	name string
+	// index is the index path of the field.
+	index []int
+	// omitempty is true if the tag of the field has the omitempty option.
+	omitempty bool
+}
+
+// mapFields returns fields of struct type t mapped to keys, with fields
+// of squashed embedded structs included. Of fields mapped to the same key
+// the least nested one is returned.
+func (o MapOptions) mapFields(t reflect.Type) []mapField {
+	fields := o.appendMapFields(nil, t, nil, map[reflect.Type]bool{t: true})
+	result := fields[:0:0]
+	for i, field := range fields {
+		shadowed := false
+		for j, other := range fields {
+			if other.name == field.name && (len(other.index) < len(field.index) ||
+				len(other.index) == len(field.index) && j < i) {
+				shadowed = true
+				break
+			}
+		}
+		if !shadowed {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// appendMapFields appends fields of struct type t under index to fields.
+// Squashing holds types of structs being squashed.
+func (o MapOptions) appendMapFields(fields []mapField, t reflect.Type, index []int, squashing map[reflect.Type]bool) []mapField {
+	for _, field := range TypeInfoOf(t).fieldList(o.StructOptions) {
+		tag := field.Tags[o.tag()]
+		if tag.Name == "-" || field.Name == "_" {
+			continue
+		}
+		fieldIndex := append(append(make([]int, 0, len(index)+len(field.Index)), index...), field.Index...)
+		if et := embeddedStruct(field.StructField); et != nil && tag.HasOption("squash") && !squashing[et] {
+			squashing[et] = true
+			fields = o.appendMapFields(fields, et, fieldIndex, squashing)
+			delete(squashing, et)
+			continue
+		}
+		name := tag.Name
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, mapField{name, fieldIndex, tag.HasOption("omitempty")})
+	}
+	return fields
+}
+
+// lookupField returns the field of fields whose key is name.
+func (o MapOptions) lookupField(fields []mapField, name string) (mapField, bool) {
+	var folded *mapField
+	for i := range fields {
+		if fields[i].name == name {
+			return fields[i], true
+		}
+		if folded == nil && o.CaseInsensitive && strings.EqualFold(fields[i].name, name) {
+			folded = &fields[i]
+		}
+	}
+	if folded == nil {
+		return mapField{}, false
+	}
+	return *folded, true
+}
+
+// tag returns the struct tag key naming fields.
+func (o MapOptions) tag() string {
+	if o.Tag == "" {
+		return "map"
+	}
+	return o.Tag
+}
+
+// StructMapOptions define how StructToMap converts structs to maps.
+type StructMapOptions struct {
+	// StructOptions define how struct fields are treated.
+	StructOptions
+	// Tag is the struct tag key whose name overrides the key of a field.
+	// If empty, "map" is used. Fields tagged "-" are excluded and fields of
+	// embedded structs tagged with the "squash" option, i.e.
+	// `map:",squash"`, are inlined. Fields whose tag has the "omitempty"
+	// option and whose value is deeply zero are omitted.
+	Tag string
+	// Recursive, if true, converts nested structs and pointers to structs
+	// to maps, and slices, arrays and maps containing them to []interface{}
+	// and map[string]interface{}. Structs implementing TextMarshaler are not
+	// converted. If false, field values are stored as they are.
+	Recursive bool
+	// MarshalText, if true, stores values implementing TextMarshaler as
+	// text they marshal to.
+	MarshalText bool
+	// Stringer, if true, stores values implementing fmt.Stringer as
+	// strings returned by String, unless stored as text by MarshalText.
+	Stringer bool
+}
+
+// StructToMap returns fields of struct v, or a struct pointed to by v, as
+// a map of field values keyed by field keys, as described by options opts.
+// Fields are selected as by LazyStructCopy.
+//
+// Nil pointers and interfaces are stored as they are. If opts.Recursive is
+// true reference cycles are stored as nil when revisited. Methods of pointer
+// receivers are used for hooks of addressable values. Values that fail to
+// marshal to text are stored as they are.
+//
+// Returns nil if v is not a struct or a pointer to one.
+func StructToMap(v interface{}, opts StructMapOptions) map[string]interface{} {
+	sv := reflect.Indirect(reflect.ValueOf(v))
+	if sv.Kind() != reflect.Struct {
+		return nil
+	}
+	return opts.structToMap(addressable(sv), make(map[visit]bool))
+}
+
+// structToMap converts struct v to a map. Visited holds pointers, maps and
+// slices being converted.
+func (o StructMapOptions) structToMap(v reflect.Value, visited map[visit]bool) map[string]interface{} {
+	mo := MapOptions{StructOptions: o.StructOptions, Tag: o.Tag}
+	m := make(map[string]interface{})
+	for _, field := range mo.mapFields(v.Type()) {
+		fv, ok := fieldByIndex(v, field.index, o.AllowUnexported)
+		if !ok || !fv.CanInterface() {
+			continue
+		}
+		if field.omitempty && IsZeroDeepValue(fv) {
+			continue
+		}
+		m[field.name] = o.value(fv, visited)
+	}
+	return m
+}
+
+// value returns v as stored by StructToMap. Visited holds pointers, maps
+// and slices being converted.
+func (o StructMapOptions) value(v reflect.Value, visited map[visit]bool) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return v.Interface()
+		}
+	}
+	if s, ok := o.hook(v); ok {
+		return s
+	}
+	if !o.Recursive || !o.converts(v.Type(), make(map[reflect.Type]bool)) {
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		ptr := visitOf(v)
+		if visited[ptr] {
+			return nil
+		}
+		visited[ptr] = true
+		defer delete(visited, ptr)
+		return o.value(v.Elem(), visited)
+	case reflect.Interface:
+		return o.value(v.Elem(), visited)
+	case reflect.Struct:
+		return o.structToMap(v, visited)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Len() > 0 {
+			key := visitOf(v)
+			if visited[key] {
+				return nil
+			}
+			visited[key] = true
+			defer delete(visited, key)
+		}
+		a := make([]interface{}, v.Len())
+		for i := range a {
+			a[i] = o.value(v.Index(i), visited)
+		}
+		return a
+	case reflect.Map:
+		key := visitOf(v)
+		if visited[key] {
+			return nil
+		}
+		visited[key] = true
+		defer delete(visited, key)
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := ValueToString(iter.Key())
+			if err != nil {
+				return v.Interface()
+			}
+			m[key] = o.value(iter.Value(), visited)
+		}
+		return m
+	}
+	return v.Interface()
+}
+
+// hook returns v as a string if it is stored as one by MarshalText or
+// Stringer options.
+func (o StructMapOptions) hook(v reflect.Value) (string, bool) {
+	if !o.MarshalText && !o.Stringer {
+		return "", false
+	}
+	ti := TypeInfoOf(v.Type())
+	if v.CanAddr() && ti.PtrImplements&^ti.Implements != 0 {
+		v = v.Addr()
+		ti = TypeInfoOf(v.Type())
+	}
+	if o.MarshalText && ti.Implements&IfaceTextMarshaler != 0 {
+		if text, err := v.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return string(text), true
+		}
+	}
+	if o.Stringer && ti.Implements&IfaceStringer != 0 {
+		return v.Interface().(fmt.Stringer).String(), true
+	}
+	return "", false
+}
+
+// converts returns true if values of type t are converted if Recursive.
+// Seen holds types being checked.
+func (o StructMapOptions) converts(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return o.converts(t.Elem(), seen)
+	case reflect.Struct:
+		ti := TypeInfoOf(t)
+		return (ti.Implements|ti.PtrImplements)&IfaceTextMarshaler == 0
+	case reflect.Interface:
+		return true
+	}
+	return false
+}
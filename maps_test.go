@@ -0,0 +1,352 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type MapsServer struct {
+	Host string `map:"host"`
+	Port int    `map:"port"`
+}
+
+type MapsCommon struct {
+	Name    string `map:"name"`
+	Verbose bool   `map:"verbose,omitempty"`
+}
+
+type MapsConfig struct {
+	MapsCommon `map:",squash"`
+	Servers    []MapsServer           `map:"servers"`
+	Primary    *MapsServer            `map:"primary"`
+	Labels     map[string]float64     `map:"labels"`
+	Since      time.Time              `map:"since"`
+	Timeout    time.Duration          `map:"timeout,omitempty"`
+	Extra      interface{}            `map:"extra"`
+	Limits     [2]uint8               `map:"limits"`
+	Nested     map[string]*MapsConfig `map:"nested,omitempty"`
+	Skip       string                 `map:"-"`
+}
+
+func TestDecodeMap(t *testing.T) {
+	var in map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"name": "app",
+		"verbose": true,
+		"servers": [{"host": "a", "port": 80}, {"host": "b", "port": 8080, "tls": true}],
+		"primary": {"host": "p", "port": 1},
+		"labels": {"x": 1.5},
+		"since": "2020-01-02T03:04:05Z",
+		"extra": [1, "2"],
+		"limits": [1, 2],
+		"nested": {"n": {"name": "inner"}},
+		"Skip": "x",
+		"unknown": 1
+	}`), &in); err != nil {
+		t.Fatal(err)
+	}
+	var meta MapMetadata
+	config := MapsConfig{Skip: "keep", Labels: map[string]float64{"y": 2}}
+	if err := DecodeMap(in, &config, MapOptions{Metadata: &meta}); err != nil {
+		t.Fatal(err)
+	}
+	want := MapsConfig{
+		MapsCommon: MapsCommon{"app", true},
+		Servers:    []MapsServer{{"a", 80}, {"b", 8080}},
+		Primary:    &MapsServer{"p", 1},
+		Labels:     map[string]float64{"x": 1.5, "y": 2},
+		Since:      time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Extra:      []interface{}{1.0, "2"},
+		Limits:     [2]uint8{1, 2},
+		Nested:     map[string]*MapsConfig{"n": {MapsCommon: MapsCommon{Name: "inner"}}},
+		Skip:       "keep",
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Fatalf("DecodeMap failed: %#v", config)
+	}
+	wantKeys := []string{"extra", "labels", "limits", "name", "nested[n].name", "nested", "primary.host", "primary.port",
+		"primary", "servers[0].host", "servers[0].port", "servers[1].host", "servers[1].port", "servers", "since", "verbose"}
+	if !reflect.DeepEqual(meta.Keys, wantKeys) {
+		t.Fatalf("DecodeMap failed, bad keys: %v", meta.Keys)
+	}
+	wantUnused := []string{"Skip", "servers[1].tls", "unknown"}
+	if !reflect.DeepEqual(meta.Unused, wantUnused) {
+		t.Fatalf("DecodeMap failed, bad unused keys: %v", meta.Unused)
+	}
+	m := map[string][]int{}
+	if err := DecodeMap(map[string]interface{}{"a": []int8{1, 2}}, &m, MapOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, map[string][]int{"a": {1, 2}}) {
+		t.Fatal("DecodeMap(map) failed")
+	}
+	merged := struct{ M map[string]interface{} }{map[string]interface{}{"keep": 1}}
+	if err := DecodeMap(map[string]interface{}{"M": map[string]interface{}{"new": 2}}, &merged, MapOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(merged.M, map[string]interface{}{"keep": 1, "new": 2}) {
+		t.Fatalf("DecodeMap failed to merge: %v", merged.M)
+	}
+	src := map[string]interface{}{"a": map[string]interface{}{"x": 1}, "b": []interface{}{1}}
+	existing := map[string]interface{}{"a": map[string]interface{}{"y": 2}, "keep": 1}
+	if err := DecodeMap(src, &existing, MapOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	src["c"], src["a"].(map[string]interface{})["z"], src["b"].([]interface{})[0] = 3, 3, 3
+	want2 := map[string]interface{}{"a": map[string]interface{}{"x": 1, "y": 2}, "b": []interface{}{1}, "keep": 1}
+	if !reflect.DeepEqual(existing, want2) {
+		t.Fatalf("DecodeMap failed to merge: %v", existing)
+	}
+	if err := DecodeMap(in, config, MapOptions{}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("DecodeMap failed, expected invalid param error")
+	}
+	if err := DecodeMap(in, new(int), MapOptions{}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("DecodeMap failed, expected invalid param error")
+	}
+}
+
+func TestDecodeMapOptions(t *testing.T) {
+	in := map[string]interface{}{"NAME": "x", "port": "8080", "Host": nil, "other": 1}
+	var server MapsServer
+	if err := DecodeMap(in, &server, MapOptions{WeaklyTyped: true}); err != nil {
+		t.Fatal(err)
+	}
+	if server != (MapsServer{Port: 8080}) {
+		t.Fatalf("DecodeMap(WeaklyTyped) failed: %#v", server)
+	}
+	var common MapsCommon
+	if err := DecodeMap(in, &common, MapOptions{CaseInsensitive: true}); err != nil || common.Name != "x" {
+		t.Fatalf("DecodeMap(CaseInsensitive) failed: %v", err)
+	}
+	var config struct {
+		MapsCommon
+		Port int `json:"port"`
+	}
+	err := DecodeMap(in, &config, MapOptions{Tag: "json", StructOptions: StructOptions{Flatten: true},
+		WeaklyTyped: true, ErrorUnused: true, CollectErrors: true})
+	var me MultiError
+	if !errors.As(err, &me) || len(me) != 3 || !errors.Is(err, ErrFieldNotFound) {
+		t.Fatalf("DecodeMap(ErrorUnused) failed: %v", err)
+	}
+	if config.Port != 8080 {
+		t.Fatal("DecodeMap(Flatten) failed")
+	}
+}
+
+func TestDecodeMapErrors(t *testing.T) {
+	tests := []struct {
+		in    map[string]interface{}
+		path  string
+		cause error
+	}{
+		{map[string]interface{}{"name": 1}, "name", ErrUnsupported},
+		{map[string]interface{}{"limits": []interface{}{1.5}}, "limits[0]", strconv.ErrRange},
+		{map[string]interface{}{"servers": []interface{}{map[string]interface{}{"host": 1}}}, "servers[0].host", ErrUnsupported},
+		{map[string]interface{}{"labels": map[string]interface{}{"x": "y"}}, "labels[x]", ErrUnsupported},
+		{map[string]interface{}{"limits": []interface{}{1, 2, 3}}, "limits", strconv.ErrRange},
+		{map[string]interface{}{"limits": []interface{}{256}}, "limits[0]", strconv.ErrRange},
+		{map[string]interface{}{"since": "x"}, "since", nil},
+		{map[string]interface{}{"primary": 1}, "primary", ErrUnsupported},
+	}
+	for i, test := range tests {
+		var config MapsConfig
+		err := DecodeMap(test.in, &config, MapOptions{})
+		var ce *ConversionError
+		if !errors.Is(err, ErrConvert) || !errors.As(err, &ce) {
+			t.Fatalf("DecodeMap failed at %d, expected conversion error: %v", i, err)
+		}
+		if ce.Path != test.path || test.cause != nil && !errors.Is(err, test.cause) {
+			t.Fatalf("DecodeMap failed at %d, bad error: %v", i, err)
+		}
+	}
+	var config MapsConfig
+	in := map[string]interface{}{
+		"limits":  []interface{}{"x"},
+		"servers": []interface{}{map[string]interface{}{"port": "y"}},
+		"unknown": 1,
+	}
+	err := DecodeMap(in, &config, MapOptions{WeaklyTyped: true, CollectErrors: true, ErrorUnused: true})
+	var me MultiError
+	if !errors.As(err, &me) || len(me) != 3 || !errors.Is(err, ErrParse) || !errors.Is(err, ErrFieldNotFound) {
+		t.Fatalf("DecodeMap(CollectErrors) failed: %v", err)
+	}
+	cyclic := map[string]interface{}{}
+	cyclic["nested"] = map[string]interface{}{"n": cyclic}
+	if err := DecodeMap(cyclic, &config, MapOptions{}); !errors.Is(err, ErrConvert) {
+		t.Fatal("DecodeMap failed, expected error on cycle")
+	}
+}
+
+func TestEncodeMap(t *testing.T) {
+	config := MapsConfig{
+		MapsCommon: MapsCommon{Name: "app"},
+		Servers:    []MapsServer{{"a", 80}},
+		Labels:     map[string]float64{"x": 1.5},
+		Since:      time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Extra:      &MapsServer{"e", 1},
+		Nested:     map[string]*MapsConfig{"n": {Skip: "x"}},
+		Skip:       "x",
+	}
+	m, err := EncodeMap(&config, MapOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested := map[string]interface{}{
+		"name": "", "servers": nil, "primary": nil, "labels": nil, "since": time.Time{},
+		"extra": nil, "limits": []interface{}{uint8(0), uint8(0)},
+	}
+	want := map[string]interface{}{
+		"name":    "app",
+		"servers": []interface{}{map[string]interface{}{"host": "a", "port": 80}},
+		"primary": nil,
+		"labels":  map[string]interface{}{"x": 1.5},
+		"since":   config.Since,
+		"extra":   map[string]interface{}{"host": "e", "port": 1},
+		"limits":  []interface{}{uint8(0), uint8(0)},
+		"nested":  map[string]interface{}{"n": nested},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("EncodeMap failed: %#v", m)
+	}
+	var decoded MapsConfig
+	if err := DecodeMap(m, &decoded, MapOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	config.Extra, config.Nested["n"].Skip, config.Skip = want["extra"], "", ""
+	if !reflect.DeepEqual(decoded, config) {
+		t.Fatalf("EncodeMap failed to round trip: %#v", decoded)
+	}
+	cyclic := map[string]interface{}{"list": []interface{}{nil}}
+	cyclic["self"] = cyclic
+	cyclic["list"].([]interface{})[0] = cyclic["list"]
+	m, err = EncodeMap(cyclic, MapOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, map[string]interface{}{"self": nil, "list": []interface{}{nil}}) {
+		t.Fatalf("EncodeMap failed on cycle: %#v", m)
+	}
+	if _, err := EncodeMap(1, MapOptions{}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("EncodeMap failed, expected invalid param error")
+	}
+	if _, err := EncodeMap(map[chan int]int{make(chan int): 1}, MapOptions{}); !errors.Is(err, ErrIncompatibleField) {
+		t.Fatal("EncodeMap failed, expected incompatible field error")
+	}
+}
+
+func FuzzDecodeMap(f *testing.F) {
+	f.Add(`{"name":"a","servers":[{"host":"b","port":1}],"labels":{"x":1},"limits":[1,2]}`, uint8(0))
+	f.Add(`{"NAME":"a","port":"80","nested":{"n":{"nested":{"m":null}}},"since":"x"}`, uint8(7))
+	f.Fuzz(func(t *testing.T, s string, flags uint8) {
+		var in map[string]interface{}
+		if json.Unmarshal([]byte(s), &in) != nil {
+			return
+		}
+		o := MapOptions{
+			WeaklyTyped:     flags&1 != 0,
+			CaseInsensitive: flags&2 != 0,
+			CollectErrors:   flags&4 != 0,
+			ErrorUnused:     flags&8 != 0,
+		}
+		var config MapsConfig
+		DecodeMap(in, &config, o)
+		m, err := EncodeMap(config, o)
+		if err != nil {
+			t.Fatalf("EncodeMap failed on decoded value: %v", err)
+		}
+		var decoded MapsConfig
+		if err := DecodeMap(m, &decoded, MapOptions{}); err != nil {
+			t.Fatalf("DecodeMap failed on encoded value: %v", err)
+		}
+	})
+}
+
+type MapsLevel int
+
+func (l *MapsLevel) String() string { return "level" + strconv.Itoa(int(*l)) }
+
+type MapsAudit struct {
+	MapsCommon `map:",squash"`
+	User       *MapsServer            `map:"user"`
+	Level      MapsLevel              `map:"level"`
+	At         time.Time              `map:"at"`
+	Servers    []*MapsServer          `map:"servers,omitempty"`
+	Labels     map[string]interface{} `map:"labels,omitempty"`
+	Self       *MapsAudit             `map:"self,omitempty"`
+	IDs        []int                  `map:"ids"`
+	_          int
+	secret     string
+}
+
+func TestStructToMap(t *testing.T) {
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	audit := &MapsAudit{
+		MapsCommon: MapsCommon{Name: "login"},
+		User:       &MapsServer{"a", 1},
+		Level:      2,
+		At:         at,
+		Labels:     map[string]interface{}{"x": MapsServer{Host: "b"}},
+		IDs:        []int{1},
+		secret:     "s",
+	}
+	audit.Self = audit
+	m := StructToMap(audit, StructMapOptions{})
+	want := map[string]interface{}{
+		"name": "login", "user": audit.User, "level": MapsLevel(2), "at": at,
+		"labels": audit.Labels, "self": audit, "ids": []int{1},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("StructToMap failed: %#v", m)
+	}
+	m = StructToMap(*audit, StructMapOptions{Recursive: true, MarshalText: true, Stringer: true})
+	want = map[string]interface{}{
+		"name":   "login",
+		"user":   map[string]interface{}{"host": "a", "port": 1},
+		"level":  "level2",
+		"at":     "2020-01-02T03:04:05Z",
+		"labels": map[string]interface{}{"x": map[string]interface{}{"host": "b", "port": 0}},
+		"self": map[string]interface{}{
+			"name":   "login",
+			"user":   map[string]interface{}{"host": "a", "port": 1},
+			"level":  "level2",
+			"at":     "2020-01-02T03:04:05Z",
+			"labels": map[string]interface{}{"x": map[string]interface{}{"host": "b", "port": 0}},
+			"self":   nil,
+			"ids":    []int{1},
+		},
+		"ids": []int{1},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("StructToMap(Recursive) failed: %#v", m)
+	}
+	m = StructToMap(MapsAudit{secret: "s"}, StructMapOptions{
+		StructOptions: StructOptions{Flatten: true, AllowUnexported: true},
+		Tag:           "json",
+		Recursive:     true,
+	})
+	want = map[string]interface{}{
+		"Name": "", "Verbose": false, "User": (*MapsServer)(nil), "Level": MapsLevel(0), "At": time.Time{},
+		"Servers": []*MapsServer(nil), "Labels": map[string]interface{}(nil), "Self": (*MapsAudit)(nil),
+		"IDs": []int(nil), "secret": "s",
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("StructToMap(Flatten) failed: %#v", m)
+	}
+	cyclic := map[string]interface{}{}
+	cyclic["self"] = cyclic
+	m = StructToMap(struct{ M map[string]interface{} }{cyclic}, StructMapOptions{Recursive: true})
+	if !reflect.DeepEqual(m, map[string]interface{}{"M": map[string]interface{}{"self": nil}}) {
+		t.Fatalf("StructToMap(Recursive) failed on cycle: %#v", m)
+	}
+	if StructToMap(1, StructMapOptions{}) != nil {
+		t.Fatal("StructToMap failed, expected nil")
+	}
+}
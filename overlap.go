@@ -0,0 +1,164 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"math"
+	"reflect"
+)
+
+// MatchKind classifies a match between two struct fields of the same name.
+type MatchKind int
+
+const (
+	// MatchNameOnly is a match by name of fields of incompatible types.
+	MatchNameOnly MatchKind = iota
+	// MatchConvertible is a match by name of fields of different types where
+	// the type of the first field is convertible to the type of the second.
+	MatchConvertible
+	// MatchExact is a match by name of fields of identical types.
+	MatchExact
+)
+
+// String implements fmt.Stringer.
+func (mk MatchKind) String() string {
+	switch mk {
+	case MatchNameOnly:
+		return "name only"
+	case MatchConvertible:
+		return "convertible"
+	case MatchExact:
+		return "exact"
+	}
+	return "unknown"
+}
+
+// weight returns the weight of a match kind in overlap score.
+func (mk MatchKind) weight() float64 {
+	switch mk {
+	case MatchConvertible:
+		return 0.5
+	case MatchExact:
+		return 1
+	}
+	return 0
+}
+
+// FieldMatch is a pair of matching fields from two structs.
+type FieldMatch struct {
+	// Name is the name the fields were matched by.
+	Name string
+	// X is the matching field of the first struct.
+	X *FieldInfo
+	// Y is the matching field of the second struct.
+	Y *FieldInfo
+	// Kind is the kind of the match.
+	Kind MatchKind
+}
+
+// Overlap describes fields two structs have in common.
+type Overlap struct {
+	// Matches are matching fields in order of declaration in the first struct.
+	Matches []FieldMatch
+	// Score is the compatibility score between 0 and 1 computed as the sum
+	// of match weights divided by the number of distinct field names in both
+	// structs. Exact matches weigh 1, convertible 0.5 and name only 0. If
+	// several fields match by the same name the best match weighs.
+	Score float64
+}
+
+// Count returns the number of matches of kind mk.
+func (o *Overlap) Count(mk MatchKind) (result int) {
+	for _, match := range o.Matches {
+		if match.Kind == mk {
+			result++
+		}
+	}
+	return
+}
+
+// OverlapOptions define how StructOverlap matches fields.
+type OverlapOptions struct {
+	// StructOptions define how struct fields are treated.
+	StructOptions
+	// Tag, if not empty, is the key of a struct tag whose name part is used
+	// to match fields instead of the field name, if set. Fields with a tag
+	// name of "-" are ignored.
+	Tag string
+}
+
+// StructOverlap returns fields that x and y which must be structs or
+// pointers to structs have in common, matched by name and classified by
+// type compatibility, and a compatibility score.
+func StructOverlap(x, y interface{}) (*Overlap, error) {
+	return OverlapOptions{}.StructOverlap(x, y)
+}
+
+// StructOverlap is StructOverlap using options o.
+func (o OverlapOptions) StructOverlap(x, y interface{}) (*Overlap, error) {
+	xv := reflect.Indirect(reflect.ValueOf(x))
+	yv := reflect.Indirect(reflect.ValueOf(y))
+	if xv.Kind() != reflect.Struct || yv.Kind() != reflect.Struct {
+		return nil, ErrInvalidParam
+	}
+	xfields := o.fieldsByName(TypeInfoOf(xv.Type()))
+	yfields := o.fieldsByName(TypeInfoOf(yv.Type()))
+	result := &Overlap{}
+	weights := make(map[string]float64)
+	for _, xfield := range TypeInfoOf(xv.Type()).fieldList(o.StructOptions) {
+		name, ok := o.name(xfield)
+		if !ok {
+			continue
+		}
+		yfield, ok := yfields[name]
+		if !ok {
+			continue
+		}
+		match := FieldMatch{name, xfield, yfield, MatchNameOnly}
+		if xfield.Type == yfield.Type {
+			match.Kind = MatchExact
+		} else if convertible(xfield.Type, yfield.Type) {
+			match.Kind = MatchConvertible
+		}
+		result.Matches = append(result.Matches, match)
+		if w, ok := weights[name]; !ok || match.Kind.weight() > w {
+			weights[name] = match.Kind.weight()
+		}
+	}
+	for _, w := range weights {
+		result.Score += w
+	}
+	if total := len(xfields) + len(yfields) - len(weights); total > 0 {
+		result.Score = math.Min(result.Score/float64(total), 1)
+	}
+	return result, nil
+}
+
+// name returns the name fi is matched by or false if fi should be ignored.
+func (o OverlapOptions) name(fi *FieldInfo) (string, bool) {
+	if o.Tag == "" {
+		return fi.Name, true
+	}
+	tag, ok := fi.Tags[o.Tag]
+	if !ok || tag.Name == "" {
+		return fi.Name, true
+	}
+	if tag.Name == "-" {
+		return "", false
+	}
+	return tag.Name, true
+}
+
+// fieldsByName returns fields of ti mapped by name they are matched by.
+func (o OverlapOptions) fieldsByName(ti *TypeInfo) map[string]*FieldInfo {
+	list := ti.fieldList(o.StructOptions)
+	result := make(map[string]*FieldInfo, len(list))
+	for _, fi := range list {
+		if name, ok := o.name(fi); ok {
+			result[name] = fi
+		}
+	}
+	return result
+}
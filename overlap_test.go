@@ -0,0 +1,102 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStructOverlap(t *testing.T) {
+
+	type (
+		X struct {
+			ID    int
+			Name  string
+			Age   int32
+			Tags  []string
+			Extra bool
+		}
+
+		Y struct {
+			ID   int
+			Name string
+			Age  int64
+			Tags map[string]string
+		}
+	)
+
+	overlap, err := StructOverlap(&X{}, Y{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overlap.Matches) != 4 {
+		t.Fatal("StructOverlap failed")
+	}
+	if overlap.Count(MatchExact) != 2 || overlap.Count(MatchConvertible) != 1 || overlap.Count(MatchNameOnly) != 1 {
+		t.Fatal("StructOverlap failed, bad classification")
+	}
+	if overlap.Matches[2].Name != "Age" || overlap.Matches[2].Kind != MatchConvertible {
+		t.Fatal("StructOverlap failed, bad match")
+	}
+	// (1 + 1 + 0.5 + 0) / 5
+	if overlap.Score != 0.5 {
+		t.Fatalf("StructOverlap failed, bad score: %f", overlap.Score)
+	}
+	if _, err := StructOverlap(42, Y{}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("StructOverlap failed, expected invalid param error")
+	}
+}
+
+func TestStructOverlapTag(t *testing.T) {
+
+	type (
+		X struct {
+			UserID int    `json:"id"`
+			Name   string `json:"-"`
+			Email  string
+		}
+
+		Y struct {
+			ID    int `json:"id"`
+			Name  string
+			Email string `json:"email"`
+		}
+	)
+
+	overlap, err := StructOverlap(X{}, Y{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overlap.Matches) != 2 {
+		t.Fatal("StructOverlap failed")
+	}
+	overlap, err = OverlapOptions{Tag: "json"}.StructOverlap(X{}, Y{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overlap.Matches) != 1 || overlap.Matches[0].Name != "id" || overlap.Matches[0].Y.Name != "ID" {
+		t.Fatal("StructOverlap(Tag) failed")
+	}
+
+	type (
+		A struct {
+			First  int `db:"id"`
+			Second int `db:"id"`
+		}
+
+		B struct {
+			ID int `db:"id"`
+		}
+	)
+
+	overlap, err = OverlapOptions{Tag: "db"}.StructOverlap(A{}, B{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overlap.Matches) != 2 || overlap.Score != 1 {
+		t.Fatalf("StructOverlap(Tag) failed on duplicate tags: %d matches, score %f", len(overlap.Matches), overlap.Score)
+	}
+}
@@ -3,12 +3,21 @@
 // license that can be found in the LICENSE file.
 
 // Package reflectex provides various reflect based utils.
+//
+// Exported functions do not panic on invalid input. They return
+// ErrInvalidParam or a typed error instead. Panics raised by methods of
+// inspected values, such as IsZero or UnmarshalText, are not recovered.
 package reflectex
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"reflect"
 	"sort"
+	"strings"
+	"sync"
+	"unsafe"
 
 	"github.com/vedranvuk/errorex"
 )
@@ -20,27 +29,76 @@ var (
 	ErrInvalidParam = ErrReflectEx.Wrap("invalid parameter")
 	// ErrParse is returned when a parse error occurs.
 	ErrParse = ErrReflectEx.Wrap("parse error")
+	// ErrParseLine is returned when a parse error occurs at a line of input.
+	ErrParseLine = ErrParse.WrapFormat("line %d")
 	// ErrUnsupported is returned when an unsupported value is encountered.
 	ErrUnsupported = ErrReflectEx.Wrap("unsupported value")
 	// ErrConvert is returned when a conversion is unable to complete.
 	ErrConvert = ErrReflectEx.WrapFormat("cannot convert '%s' to type '%s'")
+	// ErrFieldNotFound is returned when a struct field is not found by name.
+	ErrFieldNotFound = ErrReflectEx.WrapFormat("field '%s' not found")
+	// ErrDuplicateField is returned when a struct field name is not unique.
+	ErrDuplicateField = ErrReflectEx.WrapFormat("duplicate field '%s'")
+	// ErrIncompatibleField is returned when a struct field cannot be mapped
+	// to another.
+	ErrIncompatibleField = ErrReflectEx.WrapFormat("incompatible field '%s'")
+	// ErrDefault is returned when a default value of a field is invalid.
+	ErrDefault = ErrReflectEx.WrapFormat("field '%s': invalid default value")
+	// ErrValidation is the base error of struct validation failures.
+	ErrValidation = ErrReflectEx.Wrap("validation failed")
+	// ErrValidationRule is returned when a validation rule is invalid.
+	ErrValidationRule = ErrReflectEx.WrapFormat("field '%s': invalid validation rule '%s'")
 
 	// ErrNotImplemented help.
 	ErrNotImplemented = ErrReflectEx.WrapFormat("NOT IMPLEMENTED '%s'")
 )
 
+// StructOptions define how struct helpers treat struct fields.
+// Package level struct helpers use zero StructOptions.
+type StructOptions struct {
+	// Flatten, if true, replaces embedded structs with fields they promote
+	// and treats promoted fields as if declared by the embedding struct.
+	// Shadowed and ambiguous fields follow Go field selection rules; see
+	// TypeInfo.Flat.
+	//
+	// If false, an embedded struct is treated as a single field named by
+	// its type, except that LazyStructCopy still matches fields promoted to
+	// the destination.
+	//
+	// When reading, fields reachable only through a nil embedded pointer
+	// are treated as missing. When writing, nil embedded pointers are
+	// allocated as required, if settable.
+	Flatten bool
+	// AllowUnexported, if true, makes helpers read and write unexported
+	// fields as if they were exported, bypassing reflect access rules using
+	// package unsafe. Unexported fields can only be made accessible in
+	// addressable structs; helpers that take struct values copy them to
+	// addressable values first.
+	//
+	// It is meant for test fixtures and tooling that owns the types it
+	// inspects. It is off by default, in which case unexported fields are
+	// ignored.
+	AllowUnexported bool
+}
+
 // StructPartialEqual compares two structs and tells if there is at least
 // one field in both that match both by name and type.
 // Tags in both x and y are ignored.
 func StructPartialEqual(x, y interface{}) bool {
+	return StructOptions{}.StructPartialEqual(x, y)
+}
+
+// StructPartialEqual is StructPartialEqual using options o.
+func (o StructOptions) StructPartialEqual(x, y interface{}) bool {
 	xv := reflect.Indirect(reflect.ValueOf(x))
 	yv := reflect.Indirect(reflect.ValueOf(y))
 	if xv.Kind() != reflect.Struct || yv.Kind() != reflect.Struct {
 		return false
 	}
-	for i := 0; i < xv.NumField(); i++ {
-		tgt := yv.FieldByName(xv.Type().Field(i).Name)
-		if !tgt.IsValid() {
+	yti := TypeInfoOf(yv.Type())
+	for _, field := range TypeInfoOf(xv.Type()).fieldList(o) {
+		yfield, ok := yti.field(field.Name, o)
+		if !ok || yfield.Type != field.Type {
 			continue
 		}
 		return true
@@ -50,29 +108,52 @@ func StructPartialEqual(x, y interface{}) bool {
 
 // LazyStructCopy copies values from src fields that have a coresponding field
 // in dst to that field in dst. Fields must have same name and type. Tags are
-// ignored. src and dest must be of struct type and addressable.
+// ignored. src and dest must be of struct type and dst must be addressable,
+// i.e. a pointer to a struct, or ErrInvalidParam is returned.
 func LazyStructCopy(src, dst interface{}) error {
+	return StructOptions{}.LazyStructCopy(src, dst)
+}
+
+// LazyStructCopy is LazyStructCopy using options o.
+//
+// Fields promoted to dst from embedded structs match src fields by name
+// whether o.Flatten is set or not, as with reflect.Value.FieldByName. Fields
+// promoted to src are copied only if o.Flatten is true.
+func (o StructOptions) LazyStructCopy(src, dst interface{}) error {
 	srcv := reflect.Indirect(reflect.ValueOf(src))
 	dstv := reflect.Indirect(reflect.ValueOf(dst))
-	if srcv.Kind() != reflect.Struct || dstv.Kind() != reflect.Struct {
+	if srcv.Kind() != reflect.Struct || dstv.Kind() != reflect.Struct || !dstv.CanSet() {
 		return ErrInvalidParam
 	}
-	for i := 0; i < srcv.NumField(); i++ {
-		name := srcv.Type().Field(i).Name
-		tgt := dstv.FieldByName(name)
-		if !tgt.IsValid() {
+	if o.AllowUnexported {
+		srcv = addressable(srcv)
+	}
+	dstti := TypeInfoOf(dstv.Type())
+	flat := o
+	flat.Flatten = true
+	for _, field := range TypeInfoOf(srcv.Type()).fieldList(o) {
+		if field.Name == "_" {
 			continue
 		}
-		if tgt.Kind() != srcv.Field(i).Kind() {
+		dstfield, ok := dstti.field(field.Name, o)
+		if !ok && !o.Flatten {
+			dstfield, ok = dstti.field(field.Name, flat)
+		}
+		if !ok {
+			continue
+		}
+		if dstfield.Type.Kind() != field.Type.Kind() || !field.Type.AssignableTo(dstfield.Type) {
 			continue
 		}
-		if name == "_" {
+		val, ok := fieldByIndex(srcv, field.Index, o.AllowUnexported)
+		if !ok || !val.CanInterface() {
 			continue
 		}
-		if name[0] >= 97 && name[0] <= 122 {
+		tgt, ok := fieldByIndexAlloc(dstv, dstfield.Index, o.AllowUnexported)
+		if !ok || !tgt.CanSet() {
 			continue
 		}
-		tgt.Set(srcv.Field(i))
+		tgt.Set(val)
 	}
 	return nil
 }
@@ -80,8 +161,21 @@ func LazyStructCopy(src, dst interface{}) error {
 // FilterStruct returns a copy of in struct with specified fields removed.
 // In must be a pointer to a struct or a struct value.
 // Values of non-filtered fields are not copied from the source to result.
+// Unexported fields are always removed and exported fields are kept
+// whether in is a pointer or a struct value, so both yield the same type.
 // Returned value is a struct value or nil in case of an error.
+//
+// Generated struct types are cached per source type and filter set as types
+// created by reflect are never freed.
 func FilterStruct(in interface{}, filter ...string) interface{} {
+	return StructOptions{}.FilterStruct(in, filter...)
+}
+
+// FilterStruct is FilterStruct using options o. If o.Flatten is true fields
+// promoted from embedded structs are declared directly in the result.
+// O.AllowUnexported is ignored as reflect cannot create struct types with
+// unexported fields.
+func (o StructOptions) FilterStruct(in interface{}, filter ...string) interface{} {
 	v := reflect.Indirect(reflect.ValueOf(in))
 	if !v.IsValid() {
 		return nil
@@ -89,26 +183,184 @@ func FilterStruct(in interface{}, filter ...string) interface{} {
 	if v.Kind() != reflect.Struct {
 		return nil
 	}
-	sort.Strings(filter)
-	fields := make([]reflect.StructField, 0, v.NumField())
-	for i := 0; i < v.NumField(); i++ {
-		if !v.Field(i).CanSet() {
+	return reflect.New(o.filteredStructType(v.Type(), filter)).Interface()
+}
+
+// filterKey is the key of a struct type generated by FilterStruct.
+type filterKey struct {
+	// typ is the source struct type.
+	typ reflect.Type
+	// filter is the sorted filter set joined by a null character.
+	filter string
+	// opts are the options the type was generated with.
+	opts StructOptions
+}
+
+// filterCache caches struct types generated by FilterStruct.
+var filterCache sync.Map
+
+// filteredStructType returns a cached struct type derived from t with fields
+// named in filter removed, building and caching it first if required.
+func (o StructOptions) filteredStructType(t reflect.Type, filter []string) reflect.Type {
+	o.AllowUnexported = false
+	names := make([]string, len(filter))
+	copy(names, filter)
+	sort.Strings(names)
+	key := filterKey{t, strings.Join(names, "\x00"), o}
+	if cached, ok := filterCache.Load(key); ok {
+		return cached.(reflect.Type)
+	}
+	cached, _ := filterCache.LoadOrStore(key, o.buildFilteredStructType(t, names))
+	return cached.(reflect.Type)
+}
+
+// buildFilteredStructType builds a struct type from exported fields of t
+// that are not named in filter which must be sorted.
+func (o StructOptions) buildFilteredStructType(t reflect.Type, filter []string) reflect.Type {
+	list := TypeInfoOf(t).fieldList(o)
+	fields := make([]reflect.StructField, 0, len(list))
+	for _, field := range list {
+		pos := sort.SearchStrings(filter, field.Name)
+		if pos < len(filter) && filter[pos] == field.Name {
 			continue
 		}
-		pos := sort.SearchStrings(filter, v.Type().Field(i).Name)
-		if pos < len(filter) && filter[pos] == v.Type().Field(i).Name {
-			continue
+		sf := field.StructField
+		if o.Flatten {
+			sf.Anonymous = false
 		}
-		fields = append(fields, v.Type().Field(i))
+		fields = append(fields, sf)
 	}
-	structType := reflect.StructOf(fields)
-	structVal := reflect.New(structType)
-	return structVal.Interface()
+	return reflect.StructOf(fields)
 }
 
-// Show shows a reflect.Value.
+// convertible returns true if a value of type from is convertible to type to,
+// excluding conversions of integers to strings which yield a rune.
+func convertible(from, to reflect.Type) bool {
+	if to.Kind() == reflect.String {
+		switch from.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return false
+		}
+	}
+	return from.ConvertibleTo(to)
+}
+
+// convertValue converts v to type t like reflect.Value.Convert but returns
+// false instead of panicking if the conversion fails at runtime, i.e. if v
+// is a slice shorter than the array type t.
+func convertValue(v reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	if v.Kind() == reflect.Slice {
+		at := t
+		if at.Kind() == reflect.Ptr {
+			at = at.Elem()
+		}
+		if at.Kind() == reflect.Array && v.Len() < at.Len() {
+			return reflect.Value{}, false
+		}
+	}
+	return v.Convert(t), true
+}
+
+// fieldByIndex returns the nested field of struct v by index, like
+// reflect.Value.FieldByIndex, but returns false instead of panicking if
+// the field is reachable only through a nil embedded pointer. If unexported
+// is true unexported fields along the way are made accessible if
+// addressable.
+func fieldByIndex(v reflect.Value, index []int, unexported bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+		if unexported {
+			v = exposed(v)
+		}
+	}
+	return v, true
+}
+
+// fieldByIndexAlloc returns the nested field of struct v by index, like
+// reflect.Value.FieldByIndex, allocating nil embedded pointers along the way.
+// Returns false if a nil embedded pointer cannot be set. If unexported is
+// true unexported fields along the way are made accessible if addressable.
+func fieldByIndexAlloc(v reflect.Value, index []int, unexported bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+		if unexported {
+			v = exposed(v)
+		}
+	}
+	return v, true
+}
+
+// exposed returns v made accessible as if obtained through exported fields
+// if v was obtained through an unexported field and is addressable.
+// Otherwise returns v.
+func exposed(v reflect.Value) reflect.Value {
+	if v.CanInterface() || !v.CanAddr() {
+		return v
+	}
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+}
+
+// addressable returns v if addressable or an addressable copy of v.
+// Returns v if v was obtained through an unexported field and cannot be
+// copied.
+func addressable(v reflect.Value) reflect.Value {
+	if !v.IsValid() || v.CanAddr() || !v.CanInterface() {
+		return v
+	}
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+// visit is a visited reference: a pointer, a map or a slice.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	// len is the length of a slice as slices sharing an array differ by
+	// length.
+	len int
+}
+
+// visitOf returns the visit of a pointer, a map or a slice v.
+func visitOf(v reflect.Value) visit {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	return key
+}
+
+// Show shows a reflect.Value. Use Dump for a detailed recursive output.
 func Show(v reflect.Value) {
-	fmt.Printf(`Type:    %s 
+	show(os.Stdout, v)
+}
+
+// show writes v as shown by Show to w.
+func show(w io.Writer, v reflect.Value) {
+	if !v.IsValid() {
+		fmt.Fprintf(w, `Type:    <invalid>
+Kind:    %s
+IsValid: false
+`, v.Kind())
+		return
+	}
+	fmt.Fprintf(w, `Type:    %s 
 Kind:    %s 
 IsValid: %t 
 IsZero:  %t 
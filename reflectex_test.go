@@ -5,8 +5,13 @@
 package reflectex
 
 import (
+	"bytes"
+	"errors"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestStructPartialEqual(t *testing.T) {
@@ -32,6 +37,23 @@ func TestStructPartialEqual(t *testing.T) {
 	}
 }
 
+func TestStructPartialEqualType(t *testing.T) {
+
+	type (
+		X struct {
+			Field int
+		}
+
+		Y struct {
+			Field string
+		}
+	)
+
+	if StructPartialEqual(X{}, Y{}) {
+		t.Fatal("StructPartialEqual failed, matched by name only")
+	}
+}
+
 func TestLazyStructCopy(t *testing.T) {
 
 	type (
@@ -72,6 +94,219 @@ func TestFilterStruct(t *testing.T) {
 	if !reflect.DeepEqual(out, &struct{ Age int }{0}) {
 		t.Fatal("FilterStruct failed")
 	}
+	out = FilterStruct(*in, "Name", "Surname")
+	if !reflect.DeepEqual(out, &struct{ Age int }{0}) {
+		t.Fatal("FilterStruct failed on struct value")
+	}
+}
+
+func TestFilterStructCache(t *testing.T) {
+
+	type Test struct {
+		Name    string
+		Surname string
+		Age     int
+	}
+
+	a := FilterStruct(&Test{}, "Surname", "Name")
+	b := FilterStruct(Test{}, "Name", "Surname")
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		t.Fatal("FilterStruct cache failed")
+	}
+	c := FilterStruct(&Test{}, "Name")
+	if reflect.TypeOf(a) == reflect.TypeOf(c) {
+		t.Fatal("FilterStruct cache failed")
+	}
+}
+
+type EmbedBase struct {
+	ID   int
+	Name string
+}
+
+type EmbedOther struct {
+	Name  string
+	Email string
+}
+
+type EmbedDeep struct {
+	ID   string
+	Deep bool
+}
+
+type EmbedMid struct {
+	EmbedDeep
+	Mid int
+}
+
+type EmbedTest struct {
+	*EmbedBase
+	EmbedOther
+	EmbedMid
+	Email string
+}
+
+func TestStructOptionsFlatten(t *testing.T) {
+	ti := TypeInfoOf(reflect.TypeOf(EmbedTest{}))
+	names := []string{}
+	for _, fi := range ti.Flat {
+		names = append(names, fi.Name)
+	}
+	// Name is ambiguous, Email shadows EmbedOther.Email and EmbedBase.ID
+	// shadows EmbedDeep.ID.
+	if !reflect.DeepEqual(names, []string{"ID", "Deep", "Mid", "Email"}) {
+		t.Fatalf("Flat fields failed: %v", names)
+	}
+	if fi, _ := ti.FieldByName("ID"); !reflect.DeepEqual(fi.Index, []int{0, 0}) {
+		t.Fatal("Flat fields failed, bad shadowing")
+	}
+	if _, ok := ti.FieldByName("Name"); ok {
+		t.Fatal("Flat fields failed, ambiguous field found")
+	}
+}
+
+func TestStructOptionsLazyStructCopy(t *testing.T) {
+
+	type Dst struct {
+		ID    int
+		Deep  bool
+		Email string
+	}
+
+	src := &EmbedTest{Email: "foo@bar.baz"}
+	src.Deep = true
+	dst := &Dst{ID: 42}
+	// Nil embedded pointer fields are skipped.
+	if err := (StructOptions{Flatten: true}).LazyStructCopy(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if *dst != (Dst{42, true, "foo@bar.baz"}) {
+		t.Fatalf("LazyStructCopy(Flatten) failed: %v", dst)
+	}
+	// Nil embedded pointers are allocated.
+	back := &EmbedTest{}
+	if err := (StructOptions{Flatten: true}).LazyStructCopy(dst, back); err != nil {
+		t.Fatal(err)
+	}
+	if back.EmbedBase == nil || back.EmbedBase.ID != 42 || !back.Deep || back.Email != "foo@bar.baz" {
+		t.Fatal("LazyStructCopy(Flatten) failed")
+	}
+	// Fields promoted to src are not copied by default.
+	dst = &Dst{}
+	if err := LazyStructCopy(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if *dst != (Dst{0, false, "foo@bar.baz"}) {
+		t.Fatalf("LazyStructCopy failed: %v", dst)
+	}
+	// Fields promoted to dst are found by name by default.
+	type Base struct {
+		ID int
+	}
+	promoted := &struct{ Base }{}
+	if err := LazyStructCopy(&struct{ ID int }{7}, promoted); err != nil {
+		t.Fatal(err)
+	}
+	if promoted.ID != 7 {
+		t.Fatalf("LazyStructCopy failed to set promoted field: %v", promoted)
+	}
+}
+
+func TestStructOptionsStructPartialEqual(t *testing.T) {
+
+	type Test struct {
+		Mid int
+	}
+
+	if StructPartialEqual(&EmbedTest{}, &Test{}) {
+		t.Fatal("StructPartialEqual failed")
+	}
+	if !(StructOptions{Flatten: true}).StructPartialEqual(&EmbedTest{}, &Test{}) {
+		t.Fatal("StructPartialEqual(Flatten) failed")
+	}
+}
+
+func TestStructOptionsFilterStruct(t *testing.T) {
+	out := (StructOptions{Flatten: true}).FilterStruct(&EmbedTest{}, "Deep", "Mid")
+	want := &struct {
+		ID    int
+		Email string
+	}{}
+	if !reflect.DeepEqual(out, want) {
+		t.Fatalf("FilterStruct(Flatten) failed: %#v", out)
+	}
+	if reflect.TypeOf(out) == reflect.TypeOf(FilterStruct(&EmbedTest{}, "Deep", "Mid")) {
+		t.Fatal("FilterStruct(Flatten) failed, cache collision")
+	}
+	if reflect.TypeOf(FilterStruct(&EmbedTest{})).Elem().NumField() != 4 {
+		t.Fatal("FilterStruct failed")
+	}
+}
+
+func TestStructOptionsCompareValues(t *testing.T) {
+	a := &EmbedTest{EmbedBase: &EmbedBase{ID: 1}}
+	b := &EmbedTest{EmbedBase: &EmbedBase{ID: 2}}
+	c := &EmbedTest{}
+	opts := StructOptions{Flatten: true}
+	if opts.CompareInterfaces(a, b) != -1 {
+		t.Fatal("CompareInterfaces(Flatten) failed")
+	}
+	if opts.CompareInterfaces(c, c) != 0 {
+		t.Fatal("CompareInterfaces(Flatten) failed")
+	}
+	if opts.CompareInterfaces(a, c) != 1 {
+		t.Fatal("CompareInterfaces(Flatten) failed")
+	}
+}
+
+type unexportedInner struct {
+	secret string
+}
+
+type unexportedTest struct {
+	Name  string
+	count int
+	inner unexportedInner
+	ptr   *int
+}
+
+func TestStructOptionsAllowUnexported(t *testing.T) {
+	one := 1
+	src := unexportedTest{"src", 42, unexportedInner{"secret"}, &one}
+	dst := &unexportedTest{}
+	if err := LazyStructCopy(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst, &unexportedTest{Name: "src"}) {
+		t.Fatal("LazyStructCopy failed, copied unexported fields by default")
+	}
+	if CompareInterfaces(src, *dst) != 0 {
+		t.Fatal("CompareInterfaces failed, compared unexported fields by default")
+	}
+	opts := StructOptions{AllowUnexported: true}
+	if err := opts.LazyStructCopy(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst, &src) {
+		t.Fatal("LazyStructCopy(AllowUnexported) failed")
+	}
+	dst.inner.secret = "changed"
+	if opts.CompareInterfaces(src, *dst) != 1 {
+		t.Fatal("CompareInterfaces(AllowUnexported) failed")
+	}
+
+	type Other struct {
+		count int
+	}
+	if StructPartialEqual(src, Other{}) {
+		t.Fatal("StructPartialEqual failed, matched unexported fields by default")
+	}
+	if !opts.StructPartialEqual(src, Other{}) {
+		t.Fatal("StructPartialEqual(AllowUnexported) failed")
+	}
+	if reflect.TypeOf(opts.FilterStruct(src)).Elem().NumField() != 1 {
+		t.Fatal("FilterStruct(AllowUnexported) failed")
+	}
 }
 
 func BenchmarkStructPartialEqual(b *testing.B) {
@@ -186,3 +421,569 @@ func BenchmarkFilterStructFilter(b *testing.B) {
 		FilterStruct(&Test{}, "Field0", "Field1", "Field2", "Field3", "Field4", "Field5", "Field6", "Field7", "Field8", "Field9")
 	}
 }
+
+func BenchmarkFilterStructCopyUncached(b *testing.B) {
+
+	type Test struct {
+		Field0 string
+		Field1 int
+		Field2 uint
+		Field3 float32
+		Field4 float64
+		Field5 complex64
+		Field6 complex128
+		Field7 rune
+		Field8 bool
+		Field9 []byte
+	}
+
+	typ := reflect.TypeOf(Test{})
+	for i := 0; i < b.N; i++ {
+		reflect.New(StructOptions{}.buildFilteredStructType(typ, nil)).Interface()
+	}
+}
+
+func BenchmarkFilterStructFilterUncached(b *testing.B) {
+
+	type Test struct {
+		Field0 string
+		Field1 int
+		Field2 uint
+		Field3 float32
+		Field4 float64
+		Field5 complex64
+		Field6 complex128
+		Field7 rune
+		Field8 bool
+		Field9 []byte
+	}
+
+	typ := reflect.TypeOf(Test{})
+	filter := []string{"Field0", "Field1", "Field2", "Field3", "Field4", "Field5", "Field6", "Field7", "Field8", "Field9"}
+	for i := 0; i < b.N; i++ {
+		reflect.New(StructOptions{}.buildFilteredStructType(typ, filter)).Interface()
+	}
+}
+
+// FuzzStruct is a struct sample value of fuzz tests.
+type FuzzStruct struct {
+	Name   string `default:"name" validate:"required"`
+	Count  int    `validate:"min=1"`
+	Ptr    *FuzzStruct
+	Map    map[string]int
+	Slice  []interface{}
+	Iface  interface{}
+	Array  [2]int
+	hidden string
+	fuzzEmbedded
+}
+
+// fuzzEmbedded is an unexported embedded struct with a promoted field.
+type fuzzEmbedded struct {
+	Promoted string
+}
+
+// FuzzNamed is a named sample type of fuzz tests.
+type FuzzNamed int
+
+// fuzzValues returns sample values of various kinds derived from s.
+func fuzzValues(s string) []interface{} {
+	n := len(s)
+	cyclic := &FuzzStruct{Name: s}
+	cyclic.Ptr = cyclic
+	cyclicMap := map[string]interface{}{s: n}
+	cyclicMap["self"] = cyclicMap
+	cyclicSlice := []interface{}{s, nil}
+	cyclicSlice[1] = cyclicSlice
+	var cyclicIface interface{}
+	cyclicIface = &cyclicIface
+	return []interface{}{
+		nil, s, n, FuzzNamed(n), uint8(n), float32(n), complex(float64(n), 1), n%2 == 0,
+		[]byte(s), []int{n}, [2]string{s}, map[string]int{s: n}, map[interface{}]interface{}{s: n, n: nil},
+		&s, &n, (*int)(nil), (*FuzzStruct)(nil), []FuzzStruct{{Name: s}}, [1]FuzzNamed{},
+		FuzzStruct{Name: s, hidden: s}, &FuzzStruct{Name: s, Slice: []interface{}{s, n}, Iface: s},
+		cyclic, struct{}{}, func() {}, make(chan int), errors.New(s), time.Time{}, &time.Time{},
+		fuzzEmbedded{s}, &fuzzEmbedded{}, cyclicMap, cyclicSlice, cyclicIface,
+		&FuzzStruct{Name: s, Map: map[string]int{s: n}, Slice: cyclicSlice, Iface: cyclicMap},
+	}
+}
+
+// fuzzValue returns a value of fuzzValues selected by i.
+func fuzzValue(i uint8, s string) interface{} {
+	values := fuzzValues(s)
+	return values[int(i)%len(values)]
+}
+
+// fuzzReflectValue returns a reflect.Value of a value of fuzzValues
+// selected by i, including an invalid value and values obtained through an
+// unexported field, settable or not.
+func fuzzReflectValue(i uint8, s string) reflect.Value {
+	values := fuzzValues(s)
+	switch n := int(i) % (len(values) + 3); n {
+	case len(values):
+		return reflect.Value{}
+	case len(values) + 1:
+		return reflect.ValueOf(FuzzStruct{hidden: s}).FieldByName("hidden")
+	case len(values) + 2:
+		return reflect.ValueOf(&FuzzStruct{}).Elem().FieldByName("hidden")
+	default:
+		if i&0x80 != 0 && values[n] != nil {
+			v := reflect.New(reflect.TypeOf(values[n])).Elem()
+			v.Set(reflect.ValueOf(values[n]))
+			return v
+		}
+		return reflect.ValueOf(values[n])
+	}
+}
+
+// fuzzStructOptions returns StructOptions selected by flags.
+func fuzzStructOptions(flags uint8) StructOptions {
+	return StructOptions{Flatten: flags&1 != 0, AllowUnexported: flags&2 != 0}
+}
+
+// fuzzTarget is an exported entry point exercised by FuzzExported with
+// values of fuzzValues selected by x and y, options selected by flags and
+// string s.
+type fuzzTarget struct {
+	name string
+	// seeds are values of s added to the corpus besides the default seeds.
+	seeds []string
+	fn    func(t *testing.T, x, y, flags uint8, s string)
+}
+
+// fuzzTargets are the targets of FuzzExported.
+var fuzzTargets = []fuzzTarget{
+	{"StructPartialEqual", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		o := fuzzStructOptions(flags)
+		if o.StructPartialEqual(fuzzValue(x, s), fuzzValue(y, s)) != o.StructPartialEqual(fuzzValue(y, s), fuzzValue(x, s)) {
+			t.Fatal("StructPartialEqual is not symmetric")
+		}
+	}},
+	{"LazyStructCopy", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		fuzzStructOptions(flags).LazyStructCopy(fuzzValue(x, s), fuzzValue(y, s))
+		src := reflect.Indirect(reflect.ValueOf(fuzzValue(x, s)))
+		if src.Kind() != reflect.Struct {
+			return
+		}
+		o := StructOptions{AllowUnexported: true}
+		dst := reflect.New(src.Type())
+		if err := o.LazyStructCopy(src.Interface(), dst.Interface()); err != nil || o.CompareValues(dst.Elem(), src) != 0 {
+			t.Fatalf("LazyStructCopy failed to copy to own type: %v", err)
+		}
+	}},
+	{"FilterStruct", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		out := fuzzStructOptions(flags).FilterStruct(fuzzValue(x, s), s, "Name")
+		if out == nil {
+			return
+		}
+		typ := reflect.TypeOf(out).Elem()
+		for _, name := range []string{s, "Name"} {
+			if _, ok := typ.FieldByName(name); ok {
+				t.Fatalf("FilterStruct failed, field %q not filtered", name)
+			}
+		}
+	}},
+	{"Show", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		v := fuzzReflectValue(x, s)
+		sb := &strings.Builder{}
+		show(sb, v)
+		if !strings.HasPrefix(sb.String(), "Type:") || !strings.Contains(sb.String(), "Kind:    "+v.Kind().String()) {
+			t.Fatalf("Show failed: %s", sb)
+		}
+	}},
+	{"TypeInfoOf", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		if ti := TypeInfoOf(reflect.TypeOf(fuzzValue(x, s))); ti != nil {
+			ti.FieldByName(s)
+			for _, fi := range ti.Fields {
+				if found, ok := ti.FieldByName(fi.Name); fi.PkgPath == "" && (!ok || found != fi) {
+					t.Fatalf("FieldByName failed for %s", fi.Name)
+				}
+			}
+		}
+		typ := reflect.StructOf([]reflect.StructField{
+			{Name: "Field", Type: reflect.TypeOf(0), Tag: reflect.StructTag(s)},
+			{Name: "Quoted", Type: reflect.TypeOf(0), Tag: reflect.StructTag("key:" + strconv.Quote(s))},
+		})
+		for _, fi := range TypeInfoOf(typ).Fields {
+			for _, tag := range fi.Tags {
+				tag.HasOption(s)
+			}
+		}
+	}},
+	{"StructBuilder", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		typ := reflect.TypeOf(fuzzValue(x, s))
+		sb := &StructBuilder{}
+		if flags&1 != 0 {
+			sb = NewStructBuilder()
+		}
+		sb.AddField(s, typ, s).AddField("Name", typ, `json:"name"`)
+		if flags&2 != 0 {
+			sb.Embed(reflect.TypeOf(fuzzValue(y, s)))
+		}
+		if flags&4 != 0 {
+			sb.Merge(reflect.TypeOf(fuzzValue(y, s)))
+		}
+		built, err := sb.Build()
+		if err != nil {
+			return
+		}
+		if field, ok := built.FieldByName("Name"); !ok || field.Type != typ || field.Tag != `json:"name"` {
+			t.Fatalf("Build failed, bad Name field: %#v", field)
+		}
+	}},
+	{"DynamicStruct", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		ds, err := DynamicStructOf(fuzzValue(x, s))
+		if err != nil {
+			ds = &DynamicStruct{}
+			if typ := reflect.TypeOf(fuzzValue(x, s)); typ != nil {
+				if nds, err := NewDynamicStruct(typ); err == nil {
+					ds = nds
+				}
+			}
+		}
+		ds.Type()
+		ds.Interface()
+		for _, name := range []string{s, "Name", "Promoted", "hidden", "Array"} {
+			ds.Get(name)
+			v := fuzzValue(y, s)
+			if ds.Set(name, v) != nil {
+				continue
+			}
+			got, err := ds.Get(name)
+			if err == nil && reflect.TypeOf(got) == reflect.TypeOf(v) && CompareInterfaces(got, v) != 0 {
+				t.Fatalf("Get failed, got %v after setting %v", got, v)
+			}
+		}
+	}},
+	{"StructOverlap", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		o := OverlapOptions{StructOptions: fuzzStructOptions(flags)}
+		if flags&4 != 0 {
+			o.Tag = s
+		}
+		overlap, err := o.StructOverlap(fuzzValue(x, s), fuzzValue(y, s))
+		if err != nil {
+			return
+		}
+		if n := overlap.Count(MatchNameOnly) + overlap.Count(MatchConvertible) + overlap.Count(MatchExact); n != len(overlap.Matches) {
+			t.Fatalf("StructOverlap failed, counted %d of %d matches", n, len(overlap.Matches))
+		}
+		if overlap.Score < 0 || overlap.Score > 1 {
+			t.Fatalf("StructOverlap failed, score out of range: %f", overlap.Score)
+		}
+	}},
+	{"Mapper", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		src, dst := fuzzValue(x, s), fuzzValue(y, s)
+		srct, dstt := reflect.TypeOf(src), reflect.TypeOf(dst)
+		if dstt != nil && dstt.Kind() == reflect.Ptr {
+			dstt = dstt.Elem()
+		}
+		var rules []MapRule
+		switch flags >> 4 {
+		case 1:
+			rules = append(rules, Rename(s, "Name"))
+		case 2:
+			rules = append(rules, Default("Name", fuzzValue(x^y, s)))
+		case 3:
+			rules = append(rules, Converter("Name", fuzzValue(x^y, s)))
+		case 4:
+			rules = append(rules, Ignore(s), nil)
+		}
+		m := Mapper{StructOptions: fuzzStructOptions(flags), Strict: flags&4 != 0}
+		plan, err := m.Compile(srct, dstt, rules...)
+		if err != nil {
+			return
+		}
+		plan.Map(src, dst)
+		plan.MapValue(fuzzReflectValue(x, s), fuzzReflectValue(y, s))
+		a, b := reflect.New(dstt), reflect.New(dstt)
+		errA, errB := plan.Map(src, a.Interface()), plan.Map(src, b.Interface())
+		if (errA == nil) != (errB == nil) || CompareValues(a, b) != 0 {
+			t.Fatalf("Map is not deterministic: %v, %v", errA, errB)
+		}
+	}},
+	{"Validate", []string{"required", "min=1", "max=2,dive,len=1", "regex=^a", "oneof=a b", "dive,dive,required"}, func(t *testing.T, x, y, flags uint8, s string) {
+		RegisterValidationRule(s, nil)
+		vr := Validator{StructOptions: fuzzStructOptions(flags)}
+		if err := vr.Validate(fuzzValue(x, s)); err != nil && !errors.Is(err, ErrValidation) &&
+			!errors.Is(err, ErrValidationRule) && !errors.Is(err, ErrInvalidParam) {
+			t.Fatalf("Validate failed, unexpected error: %v", err)
+		}
+		v := fuzzValue(y, s)
+		if v == nil {
+			return
+		}
+		typ, err := NewStructBuilder().AddField("Value", reflect.TypeOf(v), "validate:"+strconv.Quote(s)).Build()
+		if err != nil {
+			return
+		}
+		sv := reflect.New(typ)
+		sv.Elem().Field(0).Set(reflect.ValueOf(v))
+		if err := vr.Validate(sv.Interface()); err != nil && !errors.Is(err, ErrValidation) && !errors.Is(err, ErrValidationRule) {
+			t.Fatalf("Validate failed, unexpected error: %v", err)
+		}
+	}},
+	{"SetDefaults", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		o := fuzzStructOptions(flags)
+		o.SetDefaults(fuzzValue(x, s))
+		v := fuzzValue(y, s)
+		if v == nil {
+			return
+		}
+		typ, err := NewStructBuilder().AddField("Value", reflect.TypeOf(v), "default:"+strconv.Quote(s)).Build()
+		if err != nil {
+			return
+		}
+		pv := reflect.New(typ)
+		if o.SetDefaults(pv.Interface()) != nil || typ.Field(0).Type.Kind() == reflect.Func {
+			return
+		}
+		once := reflect.New(typ)
+		once.Elem().Set(pv.Elem())
+		if err := o.SetDefaults(pv.Interface()); err != nil || !reflect.DeepEqual(pv.Elem().Interface(), once.Elem().Interface()) {
+			t.Fatalf("SetDefaults is not idempotent: %v", err)
+		}
+	}},
+	{"Zero", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		o := ZeroOptions{
+			NilOnly:         flags&1 != 0,
+			Elements:        flags&2 != 0,
+			Pointers:        flags&4 != 0,
+			ExportedOnly:    flags&8 != 0,
+			IgnoreZeroer:    flags&16 != 0,
+			AllowUnexported: flags&32 != 0,
+		}
+		o.IsZeroDeep(fuzzValue(x, s))
+		o.IsZeroDeepValue(fuzzReflectValue(x, s))
+		o.PruneZero(fuzzValue(x, s))
+		Zero(fuzzValue(y, s))
+		if v := fuzzValue(y, s); v != nil {
+			pv := reflect.New(reflect.TypeOf(v))
+			pv.Elem().Set(reflect.ValueOf(v))
+			if err := Zero(pv.Interface()); err != nil {
+				t.Fatal(err)
+			}
+			switch pv.Elem().Kind() {
+			case reflect.Ptr, reflect.Struct, reflect.Array:
+			default:
+				if !pv.Elem().IsZero() {
+					t.Fatal("Zero failed, value not zero")
+				}
+			}
+		}
+	}},
+	{"Dump", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		opts := DumpOptions{
+			MaxDepth:  int(y % 4),
+			MaxLen:    int(y / 64),
+			Addresses: flags&1 != 0,
+			Color:     flags&2 != 0,
+			Indent:    s,
+		}
+		v := fuzzValue(x, s)
+		out := Sdump(v, opts)
+		if !strings.HasSuffix(out, "\n") {
+			t.Fatalf("Sdump failed: %q", out)
+		}
+		buf := &bytes.Buffer{}
+		if err := Dump(buf, v, opts); err != nil || buf.String() != out {
+			t.Fatalf("Dump failed, differs from Sdump: %v", err)
+		}
+		SdumpValue(fuzzReflectValue(x, s), opts)
+		if err := Dump(nil, fuzzValue(x, s), opts); !errors.Is(err, ErrInvalidParam) {
+			t.Fatal("Dump failed, expected invalid param error")
+		}
+	}},
+	{"GoString", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		var o GoStringOptions
+		if flags&1 != 0 {
+			o.Hook = func(v reflect.Value) (string, bool) { return s, v.Kind() == reflect.Kind(y) }
+		}
+		v := fuzzValue(x, s)
+		if got := o.GoString(v); got != o.GoStringValue(reflect.ValueOf(v)) || got == "" && o.Hook == nil {
+			t.Fatalf("GoString failed: %q", got)
+		}
+		o.GoStringValue(fuzzReflectValue(x, s))
+	}},
+	{"Walk", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		o := WalkOptions{
+			AllowUnexported: flags&1 != 0,
+			FollowPointers:  flags&2 != 0,
+		}
+		n := 0
+		visit := func(path string, field *reflect.StructField, v reflect.Value) WalkAction {
+			n++
+			return WalkAction(int(y) * n % 3)
+		}
+		o.Walk(fuzzValue(x, s), PreOrder(visit))
+		enters, leaves := 0, 0
+		o.Walk(fuzzValue(x, s), Visitor(&countingVisitor{&enters, &leaves}))
+		if enters == 0 || enters != leaves {
+			t.Fatalf("Walk failed, %d enters and %d leaves", enters, leaves)
+		}
+		o.WalkValue(fuzzReflectValue(x, s), PostOrder(visit))
+		o.Walk(fuzzValue(x, s), nil)
+	}},
+	{"CompareValues", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		o := fuzzStructOptions(flags)
+		a, b := fuzzReflectValue(x, s), fuzzReflectValue(y, s)
+		if ab, ba := o.CompareValues(a, b), o.CompareValues(b, a); ab != -ba {
+			t.Fatalf("CompareValues is not antisymmetric: %d, %d", ab, ba)
+		}
+		if res := o.CompareInterfaces(fuzzValue(x, s), fuzzValue(x, s)); res != 0 {
+			t.Fatalf("CompareInterfaces failed, value not equal to itself: %d", res)
+		}
+	}},
+	{"StringToValue", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		converters := []func(string, reflect.Value) error{
+			StringToValue, StringToBoolValue, StringToIntValue, StringToUintValue,
+			StringToFloat32Value, StringToFloat64Value, StringToComplex64Value,
+			StringToComplex128Value, StringToStringValue, StringToArrayValue,
+			StringToSliceValue, StringToMapValue, StringToStructValue,
+			StringToPointerValue, ConvertOptions{CollectErrors: true}.StringToValue,
+		}
+		converter := converters[int(y)%len(converters)]
+		converter(s, fuzzReflectValue(x, s))
+		if v := fuzzValue(x, s); v != nil {
+			converter(s, reflect.New(reflect.TypeOf(v)).Elem())
+		}
+		StringToInterface(s, fuzzValue(x, s))
+		// Elements of compound values are not escaped so only scalars are
+		// expected to round trip.
+		v := reflect.ValueOf(fuzzValue(x, s))
+		switch v.Kind() {
+		case reflect.Invalid, reflect.Array, reflect.Slice, reflect.Map, reflect.Struct, reflect.Ptr:
+			return
+		}
+		str, err := ValueToString(v)
+		if err != nil {
+			return
+		}
+		out := reflect.New(v.Type()).Elem()
+		if StringToValue(str, out) == nil && CompareValues(out, v) != 0 {
+			t.Fatalf("StringToValue failed to round trip %q", str)
+		}
+	}},
+	{"CloneValue", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		v := fuzzValue(x, s)
+		c := CloneInterface(v)
+		if CompareInterfaces(c, v) != 0 {
+			t.Fatalf("CloneInterface failed, clone not equal: %#v", c)
+		}
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Pointer() == reflect.ValueOf(c).Pointer() {
+			t.Fatal("CloneInterface failed, pointer not copied")
+		}
+		CloneValue(fuzzReflectValue(x, s))
+	}},
+	{"Convert", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		from := fuzzValue(x, s)
+		to := reflect.TypeOf(fuzzValue(y, s))
+		o := ConvertOptions{CollectErrors: flags&1 != 0}
+		for _, in := range []interface{}{from, s} {
+			if v, err := o.Convert(in, to); err == nil && v.Type() != to {
+				t.Fatalf("Convert returned %v instead of %v", v.Type(), to)
+			}
+		}
+		if v, err := o.ConvertValue(fuzzReflectValue(x, s), to); err == nil && v.Type() != to {
+			t.Fatalf("ConvertValue returned %v instead of %v", v.Type(), to)
+		}
+		if to != nil && to.Kind() == reflect.String {
+			if v, err := o.Convert(s, to); err != nil || v.String() != s {
+				t.Fatalf("Convert failed on string %q: %v", s, err)
+			}
+		}
+		if from == nil {
+			return
+		}
+		if v, err := o.Convert(from, reflect.TypeOf(from)); err != nil {
+			t.Fatalf("Convert to own type failed: %v", err)
+		} else if v.Type() != reflect.TypeOf(from) {
+			t.Fatal("Convert to own type returned another type")
+		}
+	}},
+	{"StructToMap", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		o := StructMapOptions{
+			StructOptions: fuzzStructOptions(flags),
+			Recursive:     flags&4 != 0,
+			MarshalText:   flags&8 != 0,
+			Stringer:      flags&16 != 0,
+		}
+		v := fuzzValue(x, s)
+		if m := StructToMap(v, o); (m == nil) != (reflect.Indirect(reflect.ValueOf(v)).Kind() != reflect.Struct) {
+			t.Fatalf("StructToMap failed on %T: %v", v, m)
+		}
+		audit := &MapsAudit{Self: &MapsAudit{}, Labels: map[string]interface{}{s: fuzzValue(y, s)}}
+		labels, ok := StructToMap(audit, o)["labels"]
+		if !ok {
+			t.Fatal("StructToMap failed, labels missing")
+		}
+		if got := reflect.ValueOf(labels); got.Kind() != reflect.Map || got.Len() != 1 ||
+			!o.Recursive && got.Pointer() != reflect.ValueOf(audit.Labels).Pointer() {
+			t.Fatalf("StructToMap failed, bad labels: %#v", labels)
+		}
+	}},
+	{"ValueToString", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		ValueToString(fuzzReflectValue(x, s))
+		if str, err := InterfaceToString(fuzzValue(x, s)); err == nil && fuzzValue(x, s) != nil {
+			out := reflect.New(reflect.TypeOf(fuzzValue(x, s))).Elem()
+			StringToValue(str, out)
+		}
+	}},
+	{"EncodeValues", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		o := ValuesOptions{StructOptions: fuzzStructOptions(flags), TaggedOnly: flags&4 != 0, CaseInsensitive: flags&8 != 0}
+		v := fuzzValue(x, s)
+		values, err := o.EncodeValues(v)
+		if err != nil {
+			return
+		}
+		o.DecodeValues(values, reflect.New(reflect.TypeOf(v)).Interface())
+		o.DecodeValues(values, fuzzValue(y, s))
+	}},
+	{"EncodeMap", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		o := MapOptions{
+			StructOptions:   fuzzStructOptions(flags),
+			WeaklyTyped:     flags&4 != 0,
+			CaseInsensitive: flags&8 != 0,
+			ErrorUnused:     flags&16 != 0,
+			CollectErrors:   flags&32 != 0,
+		}
+		v := fuzzValue(x, s)
+		if m, ok := v.(map[string]interface{}); ok {
+			DecodeMap(m, fuzzValue(y, s), o)
+			DecodeMap(m, &FuzzStruct{}, o)
+		}
+		m, err := EncodeMap(v, o)
+		if err != nil {
+			return
+		}
+		DecodeMap(m, reflect.New(reflect.TypeOf(v)).Interface(), o)
+		DecodeMap(m, fuzzValue(y, s), o)
+	}},
+	{"SaveINI", nil, func(t *testing.T, x, y, flags uint8, s string) {
+		o := INIOptions{StructOptions: fuzzStructOptions(flags), CaseInsensitive: flags&4 != 0, CollectErrors: flags&8 != 0}
+		v := fuzzValue(x, s)
+		buf := &bytes.Buffer{}
+		if o.SaveINI(buf, v) != nil {
+			return
+		}
+		saved := buf.String()
+		o.LoadINI(buf, reflect.New(reflect.Indirect(reflect.ValueOf(v)).Type()).Interface())
+		o.LoadINI(strings.NewReader(saved), fuzzValue(y, s))
+	}},
+}
+
+func FuzzExported(f *testing.F) {
+	for i, target := range fuzzTargets {
+		for j := 0; j < len(fuzzValues("")); j++ {
+			f.Add(uint8(i), uint8(j), uint8(j*7+3), uint8(j), "seed")
+			f.Add(uint8(i), uint8(j)|0x80, uint8(j*5+1), uint8(j+1), "")
+			for _, s := range target.seeds {
+				f.Add(uint8(i), uint8(j), uint8(j), uint8(0), s)
+			}
+		}
+	}
+	f.Fuzz(func(t *testing.T, i, x, y, flags uint8, s string) {
+		target := fuzzTargets[int(i)%len(fuzzTargets)]
+		t.Log(target.name)
+		target.fn(t, x, y, flags, s)
+	})
+}
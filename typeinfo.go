@@ -0,0 +1,373 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Interface is a set of well known interfaces a type may implement.
+type Interface uint8
+
+const (
+	// IfaceTextMarshaler marks encoding.TextMarshaler.
+	IfaceTextMarshaler Interface = 1 << iota
+	// IfaceTextUnmarshaler marks encoding.TextUnmarshaler.
+	IfaceTextUnmarshaler
+	// IfaceStringer marks fmt.Stringer.
+	IfaceStringer
+	// IfaceError marks error.
+	IfaceError
+)
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	stringerType        = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	errorType           = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// interfacesOf returns the set of well known interfaces t implements.
+func interfacesOf(t reflect.Type) (result Interface) {
+	if t.Implements(textMarshalerType) {
+		result |= IfaceTextMarshaler
+	}
+	if t.Implements(textUnmarshalerType) {
+		result |= IfaceTextUnmarshaler
+	}
+	if t.Implements(stringerType) {
+		result |= IfaceStringer
+	}
+	if t.Implements(errorType) {
+		result |= IfaceError
+	}
+	return
+}
+
+// Tag is a parsed struct tag value of the form "name,option1,optionN".
+type Tag struct {
+	// Name is the first comma delimited element of the tag value.
+	Name string
+	// Options are the remaining comma delimited elements of the tag value.
+	Options []string
+}
+
+// HasOption returns true if tag has the named option.
+func (t Tag) HasOption(name string) bool {
+	for _, option := range t.Options {
+		if option == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldInfo holds cached metadata of a struct field.
+type FieldInfo struct {
+	// StructField is the field as returned by reflect. Its' Index is the
+	// index path of the field from the struct that owns the TypeInfo.
+	reflect.StructField
+	// Tags are the parsed struct tags of the field by key. Tags is shared
+	// by the TypeInfo cache and must not be modified.
+	Tags map[string]Tag
+}
+
+// TypeInfo holds cached metadata of a type. It is built once per type by
+// TypeInfoOf, shared by all callers and is safe for concurrent use as it is
+// never modified.
+//
+// Field slices, FieldInfo values and their Tags are shared with the cache
+// and must be treated as read-only. Callers must not modify them; copy them
+// first if modification is required.
+type TypeInfo struct {
+	// Type is the type described.
+	Type reflect.Type
+	// Implements is the set of well known interfaces Type implements.
+	Implements Interface
+	// PtrImplements is the set of well known interfaces a pointer to Type
+	// implements.
+	PtrImplements Interface
+	// Fields are exported fields of a struct Type in declaration order.
+	Fields []*FieldInfo
+	// Sorted are exported fields of a struct Type sorted by name.
+	Sorted []*FieldInfo
+	// Flat are exported fields of a struct Type with embedded structs
+	// replaced by fields they promote, in declaration order.
+	//
+	// Field visibility follows Go rules: a field shadows fields of the same
+	// name at a greater embedding depth and fields of the same name at the
+	// same depth are ambiguous and excluded.
+	Flat []*FieldInfo
+	// FlatSorted are Flat fields sorted by name.
+	FlatSorted []*FieldInfo
+	// sets are field sets of a struct Type indexed by fieldSetIndex.
+	sets [4]fieldSet
+}
+
+// fieldSet is a set of struct fields.
+type fieldSet struct {
+	// list are fields in declaration order.
+	list []*FieldInfo
+	// sorted are fields sorted by name.
+	sorted []*FieldInfo
+	// byName maps field names to field info.
+	byName map[string]*FieldInfo
+}
+
+// newFieldSet returns a fieldSet of fields in declaration order.
+func newFieldSet(fields []*FieldInfo) fieldSet {
+	fs := fieldSet{
+		list:   fields,
+		sorted: sortFieldInfos(fields),
+		byName: make(map[string]*FieldInfo, len(fields)),
+	}
+	for _, fi := range fields {
+		fs.byName[fi.Name] = fi
+	}
+	return fs
+}
+
+// fieldSetIndex returns the index of the field set of TypeInfo described by
+// options o.
+func fieldSetIndex(o StructOptions) (index int) {
+	if o.Flatten {
+		index |= 1
+	}
+	if o.AllowUnexported {
+		index |= 2
+	}
+	return
+}
+
+// FieldByName returns info of an exported field by name, including fields
+// promoted from embedded structs.
+func (ti *TypeInfo) FieldByName(name string) (*FieldInfo, bool) {
+	if fi, ok := ti.sets[0].byName[name]; ok {
+		return fi, ok
+	}
+	fi, ok := ti.sets[1].byName[name]
+	return fi, ok
+}
+
+// fieldList returns fields in declaration order as described by options o:
+// Flat fields if o.Flatten is true, Fields otherwise, including unexported
+// fields if o.AllowUnexported is true.
+func (ti *TypeInfo) fieldList(o StructOptions) []*FieldInfo {
+	return ti.sets[fieldSetIndex(o)].list
+}
+
+// sortedList returns fields of fieldList sorted by name.
+func (ti *TypeInfo) sortedList(o StructOptions) []*FieldInfo {
+	return ti.sets[fieldSetIndex(o)].sorted
+}
+
+// field returns a field of fieldList by name.
+func (ti *TypeInfo) field(name string, o StructOptions) (*FieldInfo, bool) {
+	fi, ok := ti.sets[fieldSetIndex(o)].byName[name]
+	return fi, ok
+}
+
+// typeInfos caches TypeInfo by reflect.Type.
+var typeInfos sync.Map
+
+// TypeInfoOf returns cached TypeInfo of t, building and caching it first if
+// required. Returns nil if t is nil. The result is shared and must not be
+// modified.
+func TypeInfoOf(t reflect.Type) *TypeInfo {
+	if t == nil {
+		return nil
+	}
+	if ti, ok := typeInfos.Load(t); ok {
+		return ti.(*TypeInfo)
+	}
+	ti, _ := typeInfos.LoadOrStore(t, newTypeInfo(t))
+	return ti.(*TypeInfo)
+}
+
+// newTypeInfo builds TypeInfo of t.
+func newTypeInfo(t reflect.Type) *TypeInfo {
+	ti := &TypeInfo{
+		Type:          t,
+		Implements:    interfacesOf(t),
+		PtrImplements: interfacesOf(reflect.PtrTo(t)),
+	}
+	if t.Kind() != reflect.Struct {
+		return ti
+	}
+	var exported, all []*FieldInfo
+	for i := 0; i < t.NumField(); i++ {
+		fi := newFieldInfo(t.Field(i))
+		all = append(all, fi)
+		if fi.PkgPath == "" {
+			exported = append(exported, fi)
+		}
+	}
+	ti.sets[0] = newFieldSet(exported)
+	ti.sets[1] = newFieldSet(flatFields(t, false))
+	ti.sets[2] = newFieldSet(all)
+	ti.sets[3] = newFieldSet(flatFields(t, true))
+	ti.Fields, ti.Sorted = ti.sets[0].list, ti.sets[0].sorted
+	ti.Flat, ti.FlatSorted = ti.sets[1].list, ti.sets[1].sorted
+	return ti
+}
+
+// newFieldInfo returns FieldInfo of field.
+func newFieldInfo(field reflect.StructField) *FieldInfo {
+	return &FieldInfo{
+		StructField: field,
+		Tags:        parseTags(field.Tag),
+	}
+}
+
+// sortFieldInfos returns a copy of fields sorted by name.
+func sortFieldInfos(fields []*FieldInfo) []*FieldInfo {
+	result := make([]*FieldInfo, len(fields))
+	copy(result, fields)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// embeddedStruct returns the struct type of an anonymous field of struct or
+// pointer to struct type or nil if field is not an embedded struct.
+func embeddedStruct(field reflect.StructField) reflect.Type {
+	if !field.Anonymous {
+		return nil
+	}
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// flatFields returns exported fields of struct type t with embedded structs
+// flattened, walking embedded structs breadth first by depth. If unexported
+// is true unexported fields are returned as well.
+func flatFields(t reflect.Type, unexported bool) []*FieldInfo {
+
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	var result []*FieldInfo
+	// taken are names resolved or ambiguous at a shallower depth.
+	taken := make(map[string]bool)
+	// visited are types walked at a shallower depth.
+	visited := make(map[reflect.Type]bool)
+	next := []embedded{{t, nil}}
+	for len(next) > 0 {
+		current := next
+		next = nil
+		// candidates are fields found at current depth by name.
+		candidates := make(map[string][]*FieldInfo)
+		// structs are embedded structs found at current depth by name.
+		structs := make(map[string][]embedded)
+		names := []string{}
+		for _, e := range current {
+			for i := 0; i < e.typ.NumField(); i++ {
+				field := e.typ.Field(i)
+				if taken[field.Name] {
+					continue
+				}
+				field.Index = append(append(make([]int, 0, len(e.index)+1), e.index...), i)
+				if _, ok := candidates[field.Name]; !ok {
+					names = append(names, field.Name)
+				}
+				if et := embeddedStruct(field); et != nil {
+					candidates[field.Name] = append(candidates[field.Name], nil)
+					structs[field.Name] = append(structs[field.Name], embedded{et, field.Index})
+					continue
+				}
+				candidates[field.Name] = append(candidates[field.Name], newFieldInfo(field))
+			}
+		}
+		for _, e := range current {
+			visited[e.typ] = true
+		}
+		for _, name := range names {
+			taken[name] = true
+			if len(candidates[name]) > 1 {
+				continue
+			}
+			if fi := candidates[name][0]; fi != nil {
+				if fi.PkgPath == "" || unexported {
+					result = append(result, fi)
+				}
+				continue
+			}
+			if e := structs[name][0]; !visited[e.typ] {
+				next = append(next, e)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].Index, result[j].Index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return result
+}
+
+// parseTags parses all key:"value" pairs from a struct tag.
+// Malformed tags are parsed up to the first error.
+func parseTags(tag reflect.StructTag) map[string]Tag {
+	result := make(map[string]Tag)
+	for tag != "" {
+		// Skip leading space.
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+		// Scan to colon.
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := string(tag[:i])
+		tag = tag[i+1:]
+		// Scan quoted string to find value.
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		value, err := strconv.Unquote(string(tag[:i+1]))
+		if err != nil {
+			break
+		}
+		tag = tag[i+1:]
+		parts := strings.Split(value, ",")
+		result[key] = Tag{Name: parts[0], Options: parts[1:]}
+	}
+	return result
+}
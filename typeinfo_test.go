@@ -0,0 +1,101 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type TypeInfoEmbedded struct {
+	Inner  int
+	Shadow string
+}
+
+type TypeInfoTest struct {
+	Zeta   string `json:"zeta,omitempty" db:"z"`
+	Alpha  int    `json:"alpha"`
+	hidden bool
+	Shadow int
+	*TypeInfoEmbedded
+}
+
+func TestTypeInfoOf(t *testing.T) {
+	ti := TypeInfoOf(reflect.TypeOf(TypeInfoTest{}))
+	if ti != TypeInfoOf(reflect.TypeOf(TypeInfoTest{})) {
+		t.Fatal("TypeInfoOf failed, not cached")
+	}
+	if len(ti.Fields) != 4 || ti.Fields[0].Name != "Zeta" {
+		t.Fatal("TypeInfoOf failed, bad fields")
+	}
+	names := []string{}
+	for _, fi := range ti.Sorted {
+		names = append(names, fi.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"Alpha", "Shadow", "TypeInfoEmbedded", "Zeta"}) {
+		t.Fatalf("TypeInfoOf failed, bad sort: %v", names)
+	}
+	tag := ti.Fields[0].Tags["json"]
+	if tag.Name != "zeta" || !tag.HasOption("omitempty") || tag.HasOption("string") {
+		t.Fatal("TypeInfoOf failed, bad json tag")
+	}
+	if ti.Fields[0].Tags["db"].Name != "z" {
+		t.Fatal("TypeInfoOf failed, bad db tag")
+	}
+	if _, ok := ti.FieldByName("hidden"); ok {
+		t.Fatal("TypeInfoOf failed, unexported field found")
+	}
+	fi, ok := ti.FieldByName("Inner")
+	if !ok || !reflect.DeepEqual(fi.Index, []int{4, 0}) {
+		t.Fatal("TypeInfoOf failed, bad promoted field")
+	}
+	if fi, ok := ti.FieldByName("Shadow"); !ok || len(fi.Index) != 1 || fi.Type.Kind() != reflect.Int {
+		t.Fatal("TypeInfoOf failed, bad shadowed field")
+	}
+}
+
+func TestTypeInfoOfInterfaces(t *testing.T) {
+	ti := TypeInfoOf(reflect.TypeOf(time.Time{}))
+	if ti.Implements&IfaceTextMarshaler == 0 || ti.Implements&IfaceStringer == 0 {
+		t.Fatal("TypeInfoOf failed, bad interfaces")
+	}
+	if ti.Implements&IfaceTextUnmarshaler != 0 || ti.PtrImplements&IfaceTextUnmarshaler == 0 {
+		t.Fatal("TypeInfoOf failed, bad pointer interfaces")
+	}
+	if TypeInfoOf(nil) != nil {
+		t.Fatal("TypeInfoOf failed, nil type")
+	}
+}
+
+func TestTypeInfoOfConcurrent(t *testing.T) {
+	type Test struct {
+		A, B, C int
+	}
+	typ := reflect.TypeOf(Test{})
+	result := make([]*TypeInfo, 8)
+	wg := sync.WaitGroup{}
+	for i := 0; i < len(result); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result[i] = TypeInfoOf(typ)
+		}(i)
+	}
+	wg.Wait()
+	for i := 1; i < len(result); i++ {
+		if result[i] != result[0] {
+			t.Fatal("TypeInfoOf failed, not cached")
+		}
+	}
+}
+
+func BenchmarkTypeInfoOf(b *testing.B) {
+	typ := reflect.TypeOf(TypeInfoTest{})
+	for i := 0; i < b.N; i++ {
+		TypeInfoOf(typ)
+	}
+}
@@ -0,0 +1,499 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ValidationRule is a func that checks if v satisfies a validation rule with
+// an optional rule parameter param, as specified in a struct tag.
+// It returns false if v does not satisfy the rule or an error if the rule
+// is misused, i.e. an invalid param or an unsupported value kind.
+type ValidationRule func(v reflect.Value, param string) (bool, error)
+
+var (
+	// validationRules are registered validation rules by name.
+	validationRules = map[string]ValidationRule{
+		"required": ruleRequired,
+		"min":      ruleMin,
+		"max":      ruleMax,
+		"len":      ruleLen,
+		"regex":    ruleRegex,
+		"oneof":    ruleOneOf,
+	}
+	// validationRulesMu guards validationRules.
+	validationRulesMu sync.RWMutex
+)
+
+// RegisterValidationRule registers a validation rule under name which must
+// not be empty, contain a comma or an equals sign, or be "dive". An existing
+// rule, including a built-in one, is replaced.
+func RegisterValidationRule(name string, rule ValidationRule) error {
+	if name == "" || name == "dive" || strings.ContainsAny(name, ",=") || rule == nil {
+		return ErrInvalidParam
+	}
+	validationRulesMu.Lock()
+	validationRules[name] = rule
+	validationRulesMu.Unlock()
+	return nil
+}
+
+// validationRule returns a registered validation rule by name.
+func validationRule(name string) (ValidationRule, bool) {
+	validationRulesMu.RLock()
+	rule, ok := validationRules[name]
+	validationRulesMu.RUnlock()
+	return rule, ok
+}
+
+// ValidationError describes a validation rule a field did not satisfy.
+type ValidationError struct {
+	// Path is the path to the field, i.e. "Outer.Inner[2].Field".
+	Path string
+	// Rule is the name of the unsatisfied rule.
+	Rule string
+	// Param is the parameter of the unsatisfied rule, if any.
+	Param string
+}
+
+// Error implements the error interface.
+func (ve *ValidationError) Error() string {
+	if ve.Param == "" {
+		return fmt.Sprintf("%s: rule '%s' not satisfied", ve.Path, ve.Rule)
+	}
+	return fmt.Sprintf("%s: rule '%s=%s' not satisfied", ve.Path, ve.Rule, ve.Param)
+}
+
+// Unwrap returns ErrValidation.
+func (ve *ValidationError) Unwrap() error { return ErrValidation }
+
+// ValidationErrors is a list of validation errors in order of occurence.
+type ValidationErrors []*ValidationError
+
+// Error implements the error interface.
+func (ve ValidationErrors) Error() string {
+	a := make([]string, 0, len(ve))
+	for _, err := range ve {
+		a = append(a, err.Error())
+	}
+	return strings.Join(a, "; ")
+}
+
+// Is returns true if target is ErrValidation or any of its' parents.
+func (ve ValidationErrors) Is(target error) bool {
+	return errors.Is(ErrValidation, target)
+}
+
+// ByPath returns validation errors mapped by field path.
+func (ve ValidationErrors) ByPath() map[string][]*ValidationError {
+	result := make(map[string][]*ValidationError)
+	for _, err := range ve {
+		result[err.Path] = append(result[err.Path], err)
+	}
+	return result
+}
+
+// Validator validates structs using rules defined in struct tags.
+type Validator struct {
+	// StructOptions define how struct fields are treated.
+	StructOptions
+	// Tag is the struct tag key that holds validation rules. If empty,
+	// "validate" is used.
+	Tag string
+}
+
+// Validate validates v which must be a struct or a pointer to a struct
+// using rules defined in "validate" struct tags. See Validator.Validate.
+func Validate(v interface{}) error {
+	return Validator{}.Validate(v)
+}
+
+// Validate validates v which must be a struct or a pointer to a struct
+// using rules defined in struct tags.
+//
+// Rules are comma delimited and take an optional parameter after an equals
+// sign, e.g. `validate:"required,min=1,max=10"`. Built-in rules are:
+//
+// required: value must not be zero. Strings, slices and maps must not be
+// empty and pointers must not be nil.
+//
+// min, max: numbers must not be less or greater than the parameter parsed
+// to the value type by StringToValue. Strings, arrays, slices and maps must
+// not be shorter or longer than the parameter. String length is in runes.
+//
+// len: strings, arrays, slices and maps must have the exact length.
+//
+// regex: strings must match the regular expression parameter. Regex consumes
+// the rest of the tag so it must be the last rule.
+//
+// oneof: value must equal one of space delimited parameter values, each
+// parsed to the value type by StringToValue, i.e. `validate:"oneof=1 2 3"`.
+//
+// dive: rules that follow dive apply to elements of an array, slice or map
+// instead of the field value itself. Dives may be nested.
+//
+// Rules other than required apply to values pointers point to and are
+// skipped for nil pointers. Nested structs and non-nil pointers to structs
+// are always validated, once at every path they are reachable by.
+// Pointers, and slices and maps dived into, that lead back to a value being
+// validated are skipped.
+//
+// If one or more rules are not satisfied the result is ValidationErrors
+// which responds to errors.Is(err, ErrValidation). An invalid rule or param
+// returns an error wrapping ErrValidationRule instead.
+func (vr Validator) Validate(v interface{}) error {
+	if vr.Tag == "" {
+		vr.Tag = "validate"
+	}
+	state := &validation{vr, nil, make(map[visit]bool)}
+	sv, _ := state.indirect(reflect.ValueOf(v))
+	if sv.Kind() != reflect.Struct {
+		return ErrInvalidParam
+	}
+	if err := state.validateStruct("", sv); err != nil {
+		return err
+	}
+	if len(state.errs) > 0 {
+		return state.errs
+	}
+	return nil
+}
+
+// validation is the state of a Validate call.
+type validation struct {
+	vr      Validator
+	errs    ValidationErrors
+	visited map[visit]bool
+}
+
+// ruleSpec is a parsed validation rule.
+type ruleSpec struct {
+	name  string
+	param string
+}
+
+// fieldRules are parsed validation rules of a field, by dive level.
+type fieldRules struct {
+	field  *FieldInfo
+	levels [][]ruleSpec
+}
+
+// validatorKey is the key of parsed validation rules of a type.
+type validatorKey struct {
+	typ reflect.Type
+	vr  Validator
+}
+
+// validatorCache caches parsed validation rules of struct types.
+var validatorCache sync.Map
+
+// rules returns cached validation rules of struct type t.
+func (vr Validator) rules(t reflect.Type) []fieldRules {
+	key := validatorKey{t, vr}
+	if cached, ok := validatorCache.Load(key); ok {
+		return cached.([]fieldRules)
+	}
+	list := TypeInfoOf(t).fieldList(vr.StructOptions)
+	result := make([]fieldRules, 0, len(list))
+	for _, field := range list {
+		result = append(result, fieldRules{field, parseRules(field.Tag.Get(vr.Tag))})
+	}
+	cached, _ := validatorCache.LoadOrStore(key, result)
+	return cached.([]fieldRules)
+}
+
+// parseRules parses a validation tag into rules by dive level.
+func parseRules(tag string) [][]ruleSpec {
+	levels := [][]ruleSpec{nil}
+	for tag != "" {
+		var rule string
+		if strings.HasPrefix(tag, "regex=") {
+			rule, tag = tag, ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			rule, tag = tag[:i], tag[i+1:]
+		} else {
+			rule, tag = tag, ""
+		}
+		if rule == "" {
+			continue
+		}
+		if rule == "dive" {
+			levels = append(levels, nil)
+			continue
+		}
+		spec := ruleSpec{name: rule}
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			spec.name, spec.param = rule[:i], rule[i+1:]
+		}
+		levels[len(levels)-1] = append(levels[len(levels)-1], spec)
+	}
+	return levels
+}
+
+// validateStruct validates fields of struct v at path.
+func (vs *validation) validateStruct(path string, v reflect.Value) error {
+	if vs.vr.AllowUnexported {
+		v = addressable(v)
+	}
+	for _, fr := range vs.vr.rules(v.Type()) {
+		fv, _ := fieldByIndex(v, fr.field.Index, vs.vr.AllowUnexported)
+		if err := vs.validateValue(joinPath(path, fr.field.Name), fv, fr.levels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateValue validates v at path against first level of rules and
+// elements of v against remaining levels, then validates v if a struct.
+// Invalid v is treated as a nil pointer.
+func (vs *validation) validateValue(path string, v reflect.Value, levels [][]ruleSpec) error {
+	var rules []ruleSpec
+	if len(levels) > 0 {
+		rules = levels[0]
+	}
+	for _, spec := range rules {
+		rule, ok := validationRule(spec.name)
+		if !ok {
+			return ErrValidationRule.WrapCauseArgs(ErrInvalidParam, path, spec.name)
+		}
+		rv := v
+		if spec.name != "required" {
+			rv = indirectValue(v)
+			if !rv.IsValid() {
+				continue
+			}
+		}
+		valid, err := rule(rv, spec.param)
+		if err != nil {
+			return ErrValidationRule.WrapCauseArgs(err, path, spec.name)
+		}
+		if !valid {
+			vs.errs = append(vs.errs, &ValidationError{path, spec.name, spec.param})
+		}
+	}
+	v, entered := vs.indirect(v)
+	defer vs.leave(entered)
+	if !v.IsValid() {
+		return nil
+	}
+	if len(levels) > 1 {
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() > 0 {
+			key := visitOf(v)
+			if vs.visited[key] {
+				return nil
+			}
+			vs.visited[key] = true
+			defer delete(vs.visited, key)
+		}
+		switch v.Kind() {
+		case reflect.Array, reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				if err := vs.validateValue(fmt.Sprintf("%s[%d]", path, i), v.Index(i), levels[1:]); err != nil {
+					return err
+				}
+			}
+		case reflect.Map:
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return CompareValues(keys[i], keys[j]) < 0
+			})
+			for _, key := range keys {
+				if err := vs.validateValue(fmt.Sprintf("%s[%v]", path, key), v.MapIndex(key), levels[1:]); err != nil {
+					return err
+				}
+			}
+		default:
+			return ErrValidationRule.WrapCauseArgs(ErrUnsupported, path, "dive")
+		}
+	}
+	if v.Kind() == reflect.Struct {
+		return vs.validateStruct(path, v)
+	}
+	return nil
+}
+
+// indirect dereferences pointers and interfaces of v, returning an invalid
+// value if nil or if pointing to a value being validated. It marks
+// dereferenced pointers as being validated and returns them for leave.
+func (vs *validation) indirect(v reflect.Value) (reflect.Value, []visit) {
+	var entered []visit
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}, entered
+		}
+		if v.Kind() == reflect.Ptr {
+			key := visitOf(v)
+			if vs.visited[key] {
+				return reflect.Value{}, entered
+			}
+			vs.visited[key] = true
+			entered = append(entered, key)
+		}
+		v = v.Elem()
+	}
+	return v, entered
+}
+
+// leave unmarks pointers marked by indirect so values reachable by several
+// acyclic paths are validated at every path.
+func (vs *validation) leave(entered []visit) {
+	for _, key := range entered {
+		delete(vs.visited, key)
+	}
+}
+
+// indirectValue dereferences pointers and interfaces of v, returning an
+// invalid value if nil or if a pointer leads back to itself.
+func indirectValue(v reflect.Value) reflect.Value {
+	var visited map[visit]bool
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		if v.Kind() == reflect.Ptr {
+			if visited == nil {
+				visited = make(map[visit]bool)
+			}
+			key := visitOf(v)
+			if visited[key] {
+				return reflect.Value{}
+			}
+			visited[key] = true
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// joinPath joins a field name to a path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// ruleRequired checks that v is not zero or empty.
+func ruleRequired(v reflect.Value, param string) (bool, error) {
+	if !v.IsValid() {
+		return false, nil
+	}
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() > 0, nil
+	}
+	return !v.IsZero(), nil
+}
+
+// ruleMin checks that v is not less than param.
+func ruleMin(v reflect.Value, param string) (bool, error) {
+	res, err := compareToParam(v, param)
+	return res >= 0, err
+}
+
+// ruleMax checks that v is not greater than param.
+func ruleMax(v reflect.Value, param string) (bool, error) {
+	res, err := compareToParam(v, param)
+	return res <= 0, err
+}
+
+// ruleLen checks that length of v equals param.
+func ruleLen(v reflect.Value, param string) (bool, error) {
+	if !hasLength(v) {
+		return false, ErrUnsupported
+	}
+	res, err := compareToParam(v, param)
+	return res == 0, err
+}
+
+// compareToParam compares v by value if a number or by length if a string,
+// array, slice or map to param.
+func compareToParam(v reflect.Value, param string) (int, error) {
+	if hasLength(v) {
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return 0, ErrParse
+		}
+		l := v.Len()
+		if v.Kind() == reflect.String {
+			l = utf8.RuneCountInString(v.String())
+		}
+		return compareInts(l, n), nil
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		bound := reflect.New(v.Type()).Elem()
+		if err := StringToValue(param, bound); err != nil {
+			return 0, err
+		}
+		return CompareValues(v, bound), nil
+	}
+	return 0, ErrUnsupported
+}
+
+// hasLength returns true if v is of a kind with length.
+func hasLength(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Array, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// compareInts compares two ints.
+func compareInts(a, b int) int {
+	if a > b {
+		return 1
+	}
+	if a < b {
+		return -1
+	}
+	return 0
+}
+
+// regexps caches compiled regexps by expression.
+var regexps sync.Map
+
+// ruleRegex checks that string v matches regular expression param.
+func ruleRegex(v reflect.Value, param string) (bool, error) {
+	if v.Kind() != reflect.String {
+		return false, ErrUnsupported
+	}
+	re, ok := regexps.Load(param)
+	if !ok {
+		compiled, err := regexp.Compile(param)
+		if err != nil {
+			return false, ErrParse.WrapCause(param, err)
+		}
+		re, _ = regexps.LoadOrStore(param, compiled)
+	}
+	return re.(*regexp.Regexp).MatchString(v.String()), nil
+}
+
+// ruleOneOf checks that v equals one of space delimited values in param.
+func ruleOneOf(v reflect.Value, param string) (bool, error) {
+	for _, s := range strings.Fields(param) {
+		opt := reflect.New(v.Type()).Elem()
+		if err := StringToValue(s, opt); err != nil {
+			return false, err
+		}
+		if CompareValues(v, opt) == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
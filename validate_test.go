@@ -0,0 +1,179 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type ValidateAddress struct {
+	City string `validate:"required"`
+	Zip  string `validate:"len=5,regex=^[0-9]+$"`
+}
+
+type ValidateUser struct {
+	Name     string         `validate:"required,min=2,max=8"`
+	Age      int            `validate:"min=18,max=130"`
+	Role     string         `validate:"oneof=admin user guest"`
+	Level    int            `validate:"oneof=1 2 3"`
+	Email    *string        `validate:"required"`
+	Nick     *string        `validate:"min=3"`
+	Tags     []string       `validate:"max=3,dive,required"`
+	Matrix   [][]int        `validate:"dive,dive,max=9"`
+	Scores   map[string]int `validate:"dive,min=0"`
+	Address  ValidateAddress
+	Previous *ValidateAddress
+	Others   []ValidateAddress `validate:"dive"`
+}
+
+func TestValidate(t *testing.T) {
+	email := "foo@bar.baz"
+	valid := &ValidateUser{
+		Name:    "Foo",
+		Age:     42,
+		Role:    "admin",
+		Level:   2,
+		Email:   &email,
+		Tags:    []string{"a", "b"},
+		Matrix:  [][]int{{1, 2}, {3}},
+		Scores:  map[string]int{"a": 1},
+		Address: ValidateAddress{"Zagreb", "10000"},
+	}
+	if err := Validate(valid); err != nil {
+		t.Fatal(err)
+	}
+	nick := "ab"
+	invalid := &ValidateUser{
+		Name:     "F",
+		Age:      12,
+		Role:     "root",
+		Level:    4,
+		Nick:     &nick,
+		Tags:     []string{"a", "", "c", "d"},
+		Matrix:   [][]int{{1, 10}},
+		Scores:   map[string]int{"a": -1, "b": 1},
+		Address:  ValidateAddress{"", "1000a"},
+		Previous: &ValidateAddress{"Split", "210000"},
+		Others:   []ValidateAddress{{"Rijeka", "51000"}, {}},
+	}
+	err := Validate(invalid)
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("Validate failed, expected validation error: %v", err)
+	}
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatal("Validate failed, expected ValidationErrors")
+	}
+	paths := []string{}
+	for _, ve := range errs {
+		paths = append(paths, ve.Path+":"+ve.Rule)
+	}
+	want := []string{
+		"Name:min", "Age:min", "Role:oneof", "Level:oneof", "Email:required",
+		"Nick:min", "Tags:max", "Tags[1]:required", "Matrix[0][1]:max",
+		"Scores[a]:min", "Address.City:required", "Address.Zip:regex",
+		"Previous.Zip:len", "Others[1].City:required", "Others[1].Zip:len",
+		"Others[1].Zip:regex",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("Validate failed:\nwant %v\ngot  %v", want, paths)
+	}
+	if len(errs.ByPath()["Address.Zip"]) != 1 {
+		t.Fatal("Validate failed, bad ByPath")
+	}
+	if !strings.Contains(err.Error(), "Name: rule 'min=2' not satisfied") {
+		t.Fatalf("Validate failed, bad message: %v", err)
+	}
+}
+
+func TestValidateInvalid(t *testing.T) {
+	if err := Validate(42); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("Validate failed, expected invalid param error")
+	}
+	type Unknown struct {
+		Field int `validate:"nope"`
+	}
+	if err := Validate(Unknown{}); !errors.Is(err, ErrValidationRule) {
+		t.Fatal("Validate failed, expected invalid rule error")
+	}
+	type BadParam struct {
+		Field int `validate:"min=abc"`
+	}
+	if err := Validate(BadParam{}); !errors.Is(err, ErrValidationRule) || errors.Is(err, ErrValidation) {
+		t.Fatal("Validate failed, expected invalid rule error")
+	}
+	type BadKind struct {
+		Field bool `validate:"len=1"`
+	}
+	if err := Validate(BadKind{}); !errors.Is(err, ErrValidationRule) {
+		t.Fatal("Validate failed, expected invalid rule error")
+	}
+}
+
+func TestValidateCustomRule(t *testing.T) {
+	err := RegisterValidationRule("even", func(v reflect.Value, param string) (bool, error) {
+		if v.Kind() != reflect.Int {
+			return false, ErrUnsupported
+		}
+		return v.Int()%2 == 0, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type Test struct {
+		Field int `check:"even"`
+	}
+	vr := Validator{Tag: "check"}
+	if err := vr.Validate(&Test{2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := vr.Validate(&Test{3}); !errors.Is(err, ErrValidation) {
+		t.Fatal("Validate failed, expected validation error")
+	}
+	if err := RegisterValidationRule("dive", nil); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("RegisterValidationRule failed, expected invalid param error")
+	}
+}
+
+func TestValidateCycle(t *testing.T) {
+	type Node struct {
+		Name string `validate:"required"`
+		Next *Node
+	}
+	node := &Node{}
+	node.Next = node
+	err := Validate(node)
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 1 {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	type Pair struct {
+		A, B *Node
+	}
+	shared := &Node{}
+	err = Validate(Pair{shared, shared})
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 2 || errs[0].Path != "A.Name" || errs[1].Path != "B.Name" {
+		t.Fatalf("Validate failed on shared pointer: %v", err)
+	}
+	type VNode struct {
+		Name string  `validate:"required"`
+		Kids []VNode `validate:"dive"`
+	}
+	tree := &VNode{Kids: []VNode{{}}}
+	tree.Kids[0].Kids = tree.Kids
+	err = Validate(tree)
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 2 || errs[1].Path != "Kids[0].Name" {
+		t.Fatalf("Validate failed on slice cycle: %v", err)
+	}
+	var iface interface{}
+	iface = &iface
+	if err := Validate(struct {
+		Value interface{} `validate:"min=1"`
+	}{iface}); err != nil {
+		t.Fatalf("Validate failed on interface cycle: %v", err)
+	}
+}
@@ -0,0 +1,398 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ValuesOptions define how DecodeValues and EncodeValues map url.Values
+// keys to struct fields.
+type ValuesOptions struct {
+	// StructOptions define how struct fields are treated.
+	StructOptions
+	// Tag is the struct tag key whose name overrides the key of a field.
+	// If empty, "url" is used. Fields tagged "-" are excluded.
+	Tag string
+	// TaggedOnly, if true, ignores top level fields without a tag keyed by
+	// Tag. Fields of nested structs are keyed by name if untagged.
+	TaggedOnly bool
+	// CaseInsensitive, if true, matches keys to field names ignoring case
+	// if no name matches exactly.
+	CaseInsensitive bool
+	// CollectErrors, if true, makes DecodeValues decode all keys and
+	// return all errors as a MultiError instead of returning the first
+	// error.
+	CollectErrors bool
+}
+
+// DecodeValues decodes values into a struct pointed to by out using zero
+// ValuesOptions. See ValuesOptions.DecodeValues.
+func DecodeValues(values url.Values, out interface{}) error {
+	return ValuesOptions{}.DecodeValues(values, out)
+}
+
+// DecodeValues decodes values, i.e. parsed query or form values, into a
+// struct or a map pointed to by out. Values are converted using
+// StringToValue.
+//
+// Keys name fields by the name in their tag or by their name. Bracketed
+// keys address fields of nested structs and entries of maps, i.e.
+// "filter[name]=x" sets the Name field or the "name" entry of the field
+// named "filter". Repeated keys fill slices and arrays, one element per
+// value, and a trailing "[]" in a key is ignored. Slices of bytes or runes
+// are set to a value as text. Other fields are set to the first value of a
+// key. Nil pointers along the way are allocated.
+//
+// Keys that do not address a settable field are ignored. Conversion errors
+// are returned as *ConversionError whose Path is the key, followed by the
+// index of the value for repeated keys, i.e. "ids[1]". Keys are decoded in
+// sorted order. If out is not a non-nil pointer to a struct or a map
+// ErrInvalidParam is returned.
+func (o ValuesOptions) DecodeValues(values url.Values, out interface{}) error {
+	pv := reflect.ValueOf(out)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() {
+		return ErrInvalidParam
+	}
+	v := pv.Elem()
+	if v.Kind() != reflect.Struct && v.Kind() != reflect.Map {
+		return ErrInvalidParam
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	ec := &errorCollector{collect: o.CollectErrors}
+	for _, key := range keys {
+		if err := o.decode(v, splitKey(key), values[key], key); err != nil {
+			if ec.add(err) {
+				break
+			}
+		}
+	}
+	return ec.result()
+}
+
+// decode decodes vals of key into v addressed by remaining key segments.
+func (o ValuesOptions) decode(v reflect.Value, segs, vals []string, key string) error {
+	if v.Kind() == reflect.Ptr && (len(segs) > 0 || repeated(v.Type().Elem())) {
+		if v.IsNil() {
+			if !v.CanSet() {
+				return nil
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return o.decode(v.Elem(), segs, vals, key)
+	}
+	co := ConvertOptions{CollectErrors: o.CollectErrors}
+	if len(segs) == 0 {
+		if !v.CanSet() || len(vals) == 0 {
+			return nil
+		}
+		if !repeated(v.Type()) {
+			if err := stringToValue(co, vals[0], v); err != nil {
+				return conversionError(key, vals[0], v.Type(), err)
+			}
+			return nil
+		}
+		n := len(vals)
+		var elems reflect.Value
+		if v.Kind() == reflect.Slice {
+			elems = reflect.MakeSlice(v.Type(), n, n)
+		} else {
+			elems = reflect.New(v.Type()).Elem()
+			if n > v.Len() {
+				n = v.Len()
+			}
+		}
+		ec := &errorCollector{collect: o.CollectErrors}
+		for i := 0; i < n; i++ {
+			if err := stringToValue(co, vals[i], elems.Index(i)); err != nil {
+				if ec.add(conversionError(fmt.Sprintf("%s[%d]", key, i), vals[i], v.Type().Elem(), err)) {
+					break
+				}
+			}
+		}
+		if err := ec.result(); err != nil {
+			return err
+		}
+		v.Set(elems)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		field, ok := o.valuesField(v.Type(), segs[0])
+		if !ok {
+			return nil
+		}
+		fv, ok := fieldByIndexAlloc(v, field.Index, o.AllowUnexported)
+		if !ok {
+			return nil
+		}
+		return o.nested().decode(fv, segs[1:], vals, key)
+	case reflect.Map:
+		if !v.CanSet() {
+			return nil
+		}
+		mk := reflect.New(v.Type().Key()).Elem()
+		if err := co.StringToValue(segs[0], mk); err != nil {
+			return conversionError(key, segs[0], mk.Type(), err)
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if !v.IsNil() {
+			if current := v.MapIndex(mk); current.IsValid() {
+				elem.Set(current)
+			}
+		}
+		if err := o.nested().decode(elem, segs[1:], vals, key); err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		v.SetMapIndex(mk, elem)
+	}
+	return nil
+}
+
+// EncodeValues encodes a struct or a map in to url.Values using zero
+// ValuesOptions. See ValuesOptions.EncodeValues.
+func EncodeValues(in interface{}) (url.Values, error) {
+	return ValuesOptions{}.EncodeValues(in)
+}
+
+// EncodeValues encodes a struct or a map in, or a pointer to one, to
+// url.Values that DecodeValues decodes back to an equal value. Values are
+// formatted using ValueToString.
+//
+// Fields of nested structs and entries of maps are encoded under bracketed
+// keys and elements of slices and arrays other than slices of bytes or
+// runes as repeated keys, as described by DecodeValues. Nil pointers and
+// interfaces are omitted, as are fields whose tag has the "omitempty"
+// option and whose value is deeply zero. Pointer and map cycles are
+// omitted when revisited.
+//
+// If in is not a struct or a map ErrInvalidParam is returned. If a value
+// cannot be formatted the error wraps ErrIncompatibleField naming its key.
+func (o ValuesOptions) EncodeValues(in interface{}) (url.Values, error) {
+	v := reflect.Indirect(reflect.ValueOf(in))
+	if v.Kind() != reflect.Struct && v.Kind() != reflect.Map {
+		return nil, ErrInvalidParam
+	}
+	if o.AllowUnexported {
+		v = addressable(v)
+	}
+	values := make(url.Values)
+	if err := o.encode(values, "", v, make(map[visit]bool)); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// encode encodes v to values under key. Visited holds pointers and maps
+// being encoded.
+func (o ValuesOptions) encode(values url.Values, key string, v reflect.Value, visited map[visit]bool) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	if TypeInfoOf(v.Type()).Implements&IfaceTextMarshaler != 0 {
+		return encodeValue(values, key, v)
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		ptr := visitOf(v)
+		if visited[ptr] {
+			return nil
+		}
+		visited[ptr] = true
+		defer delete(visited, ptr)
+		return o.encode(values, key, v.Elem(), visited)
+	case reflect.Interface:
+		return o.encode(values, key, v.Elem(), visited)
+	case reflect.Struct:
+		for _, field := range TypeInfoOf(v.Type()).fieldList(o.StructOptions) {
+			name := o.fieldKey(field)
+			if name == "" {
+				continue
+			}
+			fv, ok := fieldByIndex(v, field.Index, o.AllowUnexported)
+			if !ok || !fv.CanInterface() {
+				continue
+			}
+			if field.Tags[o.tag()].HasOption("omitempty") && IsZeroDeepValue(fv) {
+				continue
+			}
+			if err := o.nested().encode(values, subKey(key, name), fv, visited); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		ref := visitOf(v)
+		if visited[ref] {
+			return nil
+		}
+		visited[ref] = true
+		defer delete(visited, ref)
+		iter := v.MapRange()
+		for iter.Next() {
+			name, err := ValueToString(iter.Key())
+			if err != nil {
+				return ErrIncompatibleField.WrapCauseArgs(err, key)
+			}
+			if err := o.nested().encode(values, subKey(key, name), iter.Value(), visited); err != nil {
+				return err
+			}
+		}
+	case reflect.Array, reflect.Slice:
+		if !repeated(v.Type()) {
+			return encodeValue(values, key, v)
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeValue(values, key, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return encodeValue(values, key, v)
+	}
+	return nil
+}
+
+// encodeValue adds v formatted by ValueToString to values under key.
+func encodeValue(values url.Values, key string, v reflect.Value) error {
+	s, err := valueToText(v)
+	if err != nil {
+		return ErrIncompatibleField.WrapCauseArgs(err, key)
+	}
+	values.Add(key, s)
+	return nil
+}
+
+// tag returns the struct tag key naming fields.
+func (o ValuesOptions) tag() string {
+	if o.Tag == "" {
+		return "url"
+	}
+	return o.Tag
+}
+
+// nested returns options o for values nested in top level fields and
+// entries.
+func (o ValuesOptions) nested() ValuesOptions {
+	o.TaggedOnly = false
+	return o
+}
+
+// fieldKey returns the key of field or an empty string if field is
+// excluded.
+func (o ValuesOptions) fieldKey(field *FieldInfo) string {
+	tag, ok := field.Tags[o.tag()]
+	if !ok && o.TaggedOnly {
+		return ""
+	}
+	switch name := tag.Name; name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
+
+// valuesField returns the field of struct type t whose key is name.
+func (o ValuesOptions) valuesField(t reflect.Type, name string) (*FieldInfo, bool) {
+	var folded *FieldInfo
+	for _, field := range TypeInfoOf(t).fieldList(o.StructOptions) {
+		key := o.fieldKey(field)
+		if key == name {
+			return field, true
+		}
+		if folded == nil && o.CaseInsensitive && key != "" && strings.EqualFold(key, name) {
+			folded = field
+		}
+	}
+	return folded, folded != nil
+}
+
+// repeated returns true if values of type t are decoded from repeated keys.
+func repeated(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array || textSlice(t) {
+		return false
+	}
+	ti := TypeInfoOf(t)
+	return (ti.Implements|ti.PtrImplements)&IfaceTextUnmarshaler == 0
+}
+
+// stringType is the reflect.Type of string.
+var stringType = reflect.TypeOf("")
+
+// textSlice returns true if t is a slice of bytes or runes that is decoded
+// from and encoded to a single value as text.
+func textSlice(t reflect.Type) bool {
+	if !runesOrBytes(t) || !t.ConvertibleTo(stringType) {
+		return false
+	}
+	ti := TypeInfoOf(t)
+	return (ti.Implements|ti.PtrImplements)&(IfaceTextMarshaler|IfaceTextUnmarshaler) == 0
+}
+
+// stringToValue converts in to settable out using co, except text slices
+// which are set to in as is.
+func stringToValue(co ConvertOptions, in string, out reflect.Value) error {
+	if textSlice(out.Type()) {
+		out.Set(reflect.ValueOf(in).Convert(out.Type()))
+		return nil
+	}
+	return co.StringToValue(in, out)
+}
+
+// valueToText returns v formatted by ValueToString, except text slices
+// which are returned as they are.
+func valueToText(v reflect.Value) (string, error) {
+	if textSlice(v.Type()) {
+		return v.Convert(stringType).String(), nil
+	}
+	return ValueToString(v)
+}
+
+// splitKey splits a key of the form "name[key1][keyN]" into segments.
+// A trailing "[]" is dropped. Malformed keys are returned as a single
+// segment.
+func splitKey(key string) []string {
+	i := strings.IndexByte(key, '[')
+	if i <= 0 {
+		return []string{key}
+	}
+	segs := []string{key[:i]}
+	for rest := key[i:]; rest != ""; {
+		j := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || j < 0 {
+			return []string{key}
+		}
+		segs = append(segs, rest[1:j])
+		rest = rest[j+1:]
+	}
+	if segs[len(segs)-1] == "" {
+		segs = segs[:len(segs)-1]
+	}
+	return segs
+}
+
+// subKey returns the key of name nested under key.
+func subKey(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + "[" + name + "]"
+}
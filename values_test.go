@@ -0,0 +1,238 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type ValuesFilter struct {
+	Name string   `url:"name"`
+	Tags []string `url:"tag"`
+}
+
+type ValuesQuery struct {
+	Page   int               `url:"page"`
+	IDs    []int             `url:"id"`
+	Filter ValuesFilter      `url:"filter"`
+	Sort   map[string]string `url:"sort"`
+	Since  *time.Time        `url:"since,omitempty"`
+	Range  *[2]float64       `url:"range,omitempty"`
+	Nested map[string]*ValuesFilter
+	Skip   string `url:"-"`
+}
+
+func TestDecodeValues(t *testing.T) {
+	values, err := url.ParseQuery("page=2&id=1&id=2&id=3&filter[name]=x&filter[tag][]=a&filter[tag][]=b" +
+		"&sort[name]=asc&since=2020-01-02T03:04:05Z&range=1.5&range=2.5&Nested[a][name]=y&Skip=1&unknown=1&page[x]=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var query ValuesQuery
+	if err := DecodeValues(values, &query); err != nil {
+		t.Fatal(err)
+	}
+	since := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	want := ValuesQuery{
+		Page:   2,
+		IDs:    []int{1, 2, 3},
+		Filter: ValuesFilter{"x", []string{"a", "b"}},
+		Sort:   map[string]string{"name": "asc"},
+		Since:  &since,
+		Range:  &[2]float64{1.5, 2.5},
+		Nested: map[string]*ValuesFilter{"a": {Name: "y"}},
+	}
+	if !reflect.DeepEqual(query, want) {
+		t.Fatalf("DecodeValues failed: %#v", query)
+	}
+	m := map[string][]int{}
+	if err := DecodeValues(url.Values{"a": {"1", "2"}}, &m); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, map[string][]int{"a": {1, 2}}) {
+		t.Fatal("DecodeValues(map) failed")
+	}
+	var text struct {
+		Data  []byte
+		Runes []rune
+		Lines [][]byte
+	}
+	if err := DecodeValues(url.Values{"Data": {"hello"}, "Runes": {"wörld"}, "Lines": {"a", "b"}}, &text); err != nil {
+		t.Fatal(err)
+	}
+	if string(text.Data) != "hello" || string(text.Runes) != "wörld" || len(text.Lines) != 2 || string(text.Lines[1]) != "b" {
+		t.Fatalf("DecodeValues(bytes) failed: %#v", text)
+	}
+	values, err = EncodeValues(text)
+	if err != nil || !reflect.DeepEqual(values, url.Values{"Data": {"hello"}, "Runes": {"wörld"}, "Lines": {"a", "b"}}) {
+		t.Fatalf("EncodeValues(bytes) failed: %v %v", values, err)
+	}
+	if err := DecodeValues(values, query); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("DecodeValues failed, expected invalid param error")
+	}
+	if err := DecodeValues(values, new(int)); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("DecodeValues failed, expected invalid param error")
+	}
+}
+
+func TestDecodeValuesErrors(t *testing.T) {
+	values := url.Values{"page": {"x"}, "id": {"1", "y", "z"}, "sort[name]": {"asc"}}
+	var query ValuesQuery
+	err := DecodeValues(values, &query)
+	var ce *ConversionError
+	if !errors.As(err, &ce) || ce.Path != "id[1]" || ce.Input != "y" {
+		t.Fatalf("DecodeValues failed, expected first error: %v", err)
+	}
+	query = ValuesQuery{}
+	err = ValuesOptions{CollectErrors: true}.DecodeValues(values, &query)
+	var me MultiError
+	if !errors.As(err, &me) || len(me) != 3 || !errors.Is(err, ErrParse) {
+		t.Fatalf("DecodeValues(CollectErrors) failed: %v", err)
+	}
+	if query.Sort["name"] != "asc" {
+		t.Fatal("DecodeValues(CollectErrors) failed, valid keys not decoded")
+	}
+	if err := DecodeValues(url.Values{"a": {"1"}}, &map[int]int{}); !errors.Is(err, ErrConvert) {
+		t.Fatal("DecodeValues failed, expected map key conversion error")
+	}
+}
+
+func TestValuesOptions(t *testing.T) {
+
+	type Test struct {
+		Name   string `form:"user_name"`
+		Count  int
+		hidden int
+	}
+
+	values := url.Values{"USER_NAME": {"x"}, "count": {"1"}, "hidden": {"2"}}
+	var test Test
+	if err := (ValuesOptions{Tag: "form"}).DecodeValues(values, &test); err != nil {
+		t.Fatal(err)
+	}
+	if test != (Test{}) {
+		t.Fatal("DecodeValues failed, matched case-insensitively by default")
+	}
+	o := ValuesOptions{
+		StructOptions:   StructOptions{AllowUnexported: true},
+		Tag:             "form",
+		CaseInsensitive: true,
+	}
+	if err := o.DecodeValues(values, &test); err != nil {
+		t.Fatal(err)
+	}
+	if test != (Test{"x", 1, 2}) {
+		t.Fatalf("DecodeValues(CaseInsensitive) failed: %#v", test)
+	}
+	o.TaggedOnly = true
+	test = Test{}
+	if err := o.DecodeValues(values, &test); err != nil {
+		t.Fatal(err)
+	}
+	if test != (Test{Name: "x"}) {
+		t.Fatalf("DecodeValues(TaggedOnly) failed: %#v", test)
+	}
+	o.TaggedOnly = false
+	if err := o.DecodeValues(values, &test); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := o.EncodeValues(test)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(encoded, url.Values{"user_name": {"x"}, "Count": {"1"}, "hidden": {"2"}}) {
+		t.Fatalf("EncodeValues(AllowUnexported) failed: %v", encoded)
+	}
+}
+
+func TestEncodeValues(t *testing.T) {
+	since := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	query := &ValuesQuery{
+		Page:   2,
+		IDs:    []int{1, 2, 3},
+		Filter: ValuesFilter{"x", []string{"a", "b"}},
+		Sort:   map[string]string{"name": "asc"},
+		Since:  &since,
+		Nested: map[string]*ValuesFilter{"a": {Name: "y"}, "b": nil},
+		Skip:   "skip",
+	}
+	values, err := EncodeValues(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"page":            {"2"},
+		"id":              {"1", "2", "3"},
+		"filter[name]":    {"x"},
+		"filter[tag]":     {"a", "b"},
+		"sort[name]":      {"asc"},
+		"since":           {"2020-01-02T03:04:05Z"},
+		"Nested[a][name]": {"y"},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Fatalf("EncodeValues failed: %v", values)
+	}
+	var decoded ValuesQuery
+	if err := DecodeValues(values, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	query.Nested, query.Skip = map[string]*ValuesFilter{"a": {Name: "y"}}, ""
+	if !reflect.DeepEqual(&decoded, query) {
+		t.Fatalf("EncodeValues failed, round trip not equal: %#v", decoded)
+	}
+	if _, err := EncodeValues(1); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("EncodeValues failed, expected invalid param error")
+	}
+	if _, err := EncodeValues(map[string][]func(){"a": {nil}}); !errors.Is(err, ErrIncompatibleField) {
+		t.Fatal("EncodeValues failed, expected incompatible field error")
+	}
+	cycle := map[string]interface{}{"a": 1}
+	cycle["self"] = cycle
+	values, err = EncodeValues(cycle)
+	if err != nil || !reflect.DeepEqual(values, url.Values{"a": {"1"}}) {
+		t.Fatalf("EncodeValues failed on map cycle: %v %v", values, err)
+	}
+}
+
+func TestSplitKey(t *testing.T) {
+	tests := map[string][]string{
+		"a":          {"a"},
+		"a[b]":       {"a", "b"},
+		"a[b][c]":    {"a", "b", "c"},
+		"a[]":        {"a"},
+		"a[b][]":     {"a", "b"},
+		"a[b":        {"a[b"},
+		"a[b]c":      {"a[b]c"},
+		"[a]":        {"[a]"},
+		"a[b[c]][d]": {"a[b[c]][d]"},
+	}
+	for in, want := range tests {
+		if got := splitKey(in); !reflect.DeepEqual(got, want) {
+			t.Fatalf("splitKey(%q) failed: %q", in, got)
+		}
+	}
+}
+
+func FuzzDecodeValues(f *testing.F) {
+	f.Add("page=2&id=1&id=2&filter[name]=x&sort[a]=b&Nested[a][tag][]=c")
+	f.Add("a[b][c]=1&a[]=2&[x]=3")
+	f.Fuzz(func(t *testing.T, s string) {
+		values, err := url.ParseQuery(s)
+		if err != nil {
+			return
+		}
+		var query ValuesQuery
+		DecodeValues(values, &query)
+		if _, err := EncodeValues(query); err != nil {
+			t.Fatalf("EncodeValues failed on decoded value: %v", err)
+		}
+		m := map[string]map[int][]*int{}
+		ValuesOptions{CollectErrors: true}.DecodeValues(values, &m)
+	})
+}
@@ -0,0 +1,221 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// WalkAction tells Walk how to proceed after visiting a value.
+type WalkAction int
+
+const (
+	// Continue continues the walk.
+	Continue WalkAction = iota
+	// SkipChildren skips children of the entered value. If returned from
+	// Leave it is the same as Continue.
+	SkipChildren
+	// Stop stops the walk.
+	Stop
+)
+
+// Visitor is visited by Walk for every walked value.
+//
+// Path is the path to the value from the walked value, i.e.
+// "Field.Slice[2].Map[key]", empty for the walked value. Field is the struct
+// field of the value if it is a struct field, nil otherwise. V is the value.
+type Visitor interface {
+	// Enter is called when a value is entered, before its children.
+	Enter(path string, field *reflect.StructField, v reflect.Value) WalkAction
+	// Leave is called when a value is left, after its children.
+	Leave(path string, field *reflect.StructField, v reflect.Value) WalkAction
+}
+
+// VisitFunc is a func visiting a walked value.
+// See Visitor for description of arguments.
+type VisitFunc func(path string, field *reflect.StructField, v reflect.Value) WalkAction
+
+// preOrder is a Visitor calling a VisitFunc on Enter.
+type preOrder VisitFunc
+
+func (po preOrder) Enter(path string, field *reflect.StructField, v reflect.Value) WalkAction {
+	return po(path, field, v)
+}
+
+func (po preOrder) Leave(string, *reflect.StructField, reflect.Value) WalkAction { return Continue }
+
+// postOrder is a Visitor calling a VisitFunc on Leave.
+type postOrder VisitFunc
+
+func (po postOrder) Enter(string, *reflect.StructField, reflect.Value) WalkAction { return Continue }
+
+func (po postOrder) Leave(path string, field *reflect.StructField, v reflect.Value) WalkAction {
+	return po(path, field, v)
+}
+
+// PreOrder returns a Visitor that calls fn for values before their children.
+func PreOrder(fn VisitFunc) Visitor { return preOrder(fn) }
+
+// PostOrder returns a Visitor that calls fn for values after their children.
+func PostOrder(fn VisitFunc) Visitor { return postOrder(fn) }
+
+// WalkOptions define how Walk walks values.
+type WalkOptions struct {
+	// AllowUnexported, if true, walks unexported struct fields made
+	// accessible as if exported, using package unsafe, so they can be set
+	// and converted to interfaces. A walked struct that is not addressable
+	// is copied first. See StructOptions.AllowUnexported.
+	AllowUnexported bool
+	// FollowPointers, if true, walks values pointers point to. Pointers
+	// already being walked are not followed, which stops on cycles.
+	FollowPointers bool
+}
+
+// Walk walks v recursively using zero WalkOptions, calling visitor for each
+// walked value. See WalkOptions.WalkValue.
+func Walk(v interface{}, visitor Visitor) error {
+	return WalkOptions{}.WalkValue(reflect.ValueOf(v), visitor)
+}
+
+// Walk walks v recursively using options o. See WalkValue.
+func (o WalkOptions) Walk(v interface{}, visitor Visitor) error {
+	return o.WalkValue(reflect.ValueOf(v), visitor)
+}
+
+// WalkValue walks v recursively using options o, calling visitor for each
+// walked value.
+//
+// Walked children are struct fields, array and slice elements, map values
+// sorted by key and values contained in interfaces, which share the path
+// of the interface. Values pointers point to are children of pointers if
+// FollowPointers is set. The walked value, if a pointer, is always followed.
+// Children of maps and slices already being walked are not walked again,
+// which stops on reference cycles.
+func (o WalkOptions) WalkValue(v reflect.Value, visitor Visitor) error {
+	if visitor == nil {
+		return ErrInvalidParam
+	}
+	w := &walker{o, visitor, make(map[visit]bool)}
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		w.visited[visitOf(v)] = true
+		v = v.Elem()
+	}
+	if o.AllowUnexported {
+		v = addressable(v)
+	}
+	w.walk("", nil, v)
+	return nil
+}
+
+// walker is the state of a Walk call.
+type walker struct {
+	opts    WalkOptions
+	visitor Visitor
+	visited map[visit]bool
+}
+
+// walk walks v at path and returns false if the walk should stop.
+func (w *walker) walk(path string, field *reflect.StructField, v reflect.Value) bool {
+	switch w.visitor.Enter(path, field, v) {
+	case Stop:
+		return false
+	case SkipChildren:
+		return w.visitor.Leave(path, field, v) != Stop
+	}
+	if !w.children(path, v) {
+		return false
+	}
+	return w.visitor.Leave(path, field, v) != Stop
+}
+
+// children walks children of v at path and returns false if the walk
+// should stop.
+func (w *walker) children(path string, v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || !w.opts.FollowPointers {
+			return true
+		}
+		key := visitOf(v)
+		if w.visited[key] {
+			return true
+		}
+		w.visited[key] = true
+		defer delete(w.visited, key)
+		return w.walk(path, nil, v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			return true
+		}
+		return w.walk(path, nil, v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			sf := v.Type().Field(i)
+			if sf.PkgPath != "" && !w.opts.AllowUnexported {
+				continue
+			}
+			fv := v.Field(i)
+			if w.opts.AllowUnexported {
+				fv = exposed(fv)
+			}
+			if !w.walk(joinPath(path, sf.Name), &sf, fv) {
+				return false
+			}
+		}
+	case reflect.Array, reflect.Slice:
+		if v.Kind() == reflect.Slice && v.Len() > 0 {
+			key := visitOf(v)
+			if w.visited[key] {
+				return true
+			}
+			w.visited[key] = true
+			defer delete(w.visited, key)
+		}
+		for i := 0; i < v.Len(); i++ {
+			if !w.walk(fmt.Sprintf("%s[%d]", path, i), nil, v.Index(i)) {
+				return false
+			}
+		}
+	case reflect.Map:
+		if v.Len() == 0 {
+			return true
+		}
+		key := visitOf(v)
+		if w.visited[key] {
+			return true
+		}
+		w.visited[key] = true
+		defer delete(w.visited, key)
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return CompareValues(keys[i], keys[j]) < 0
+		})
+		for _, key := range keys {
+			if !w.walk(fmt.Sprintf("%s[%v]", path, mapKeyString(key)), nil, v.MapIndex(key)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// mapKeyString returns map key v formatted for use in a path.
+func mapKeyString(v reflect.Value) string {
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	if s := scalarString(v); s != "" {
+		return s
+	}
+	if v.CanInterface() {
+		return fmt.Sprint(v.Interface())
+	}
+	return v.Type().String()
+}
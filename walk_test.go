@@ -0,0 +1,171 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type WalkNode struct {
+	Name   string
+	Items  []int
+	Attrs  map[string]interface{}
+	Next   *WalkNode
+	hidden bool
+}
+
+// walkRecorder records visited paths.
+type walkRecorder struct {
+	paths []string
+}
+
+func (wr *walkRecorder) Enter(path string, field *reflect.StructField, v reflect.Value) WalkAction {
+	wr.paths = append(wr.paths, ">"+path)
+	return Continue
+}
+
+func (wr *walkRecorder) Leave(path string, field *reflect.StructField, v reflect.Value) WalkAction {
+	wr.paths = append(wr.paths, "<"+path)
+	return Continue
+}
+
+func TestWalk(t *testing.T) {
+	node := &WalkNode{
+		Name:  "root",
+		Items: []int{1},
+		Attrs: map[string]interface{}{"b": 2, "a": 1},
+		Next:  &WalkNode{Name: "next"},
+	}
+	wr := &walkRecorder{}
+	if err := Walk(node, wr); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		">", ">Name", "<Name", ">Items", ">Items[0]", "<Items[0]", "<Items",
+		">Attrs", ">Attrs[a]", ">Attrs[a]", "<Attrs[a]", "<Attrs[a]",
+		">Attrs[b]", ">Attrs[b]", "<Attrs[b]", "<Attrs[b]", "<Attrs",
+		">Next", "<Next", "<",
+	}
+	if !reflect.DeepEqual(wr.paths, want) {
+		t.Fatalf("Walk failed:\nwant %v\ngot  %v", want, wr.paths)
+	}
+	if err := Walk(node, nil); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("Walk failed, expected invalid param error")
+	}
+}
+
+func TestWalkOptions(t *testing.T) {
+	node := &WalkNode{Name: "root"}
+	node.Next = node
+	names := []string{}
+	err := WalkOptions{AllowUnexported: true, FollowPointers: true}.Walk(node, PreOrder(
+		func(path string, field *reflect.StructField, v reflect.Value) WalkAction {
+			if field != nil {
+				names = append(names, path)
+			}
+			return Continue
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Name", "Items", "Attrs", "Next", "hidden"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("Walk failed:\nwant %v\ngot  %v", want, names)
+	}
+}
+
+func TestWalkCycle(t *testing.T) {
+	m := map[string]interface{}{}
+	m["self"] = m
+	s := []interface{}{nil, 1}
+	s[0] = s
+	tests := []struct {
+		in   interface{}
+		want []string
+	}{
+		{m, []string{"", "[self]", "[self]"}},
+		{s, []string{"", "[0]", "[0]", "[1]", "[1]"}},
+	}
+	for _, test := range tests {
+		paths := []string{}
+		err := Walk(test.in, PreOrder(
+			func(path string, field *reflect.StructField, v reflect.Value) WalkAction {
+				paths = append(paths, path)
+				return Continue
+			}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(paths, test.want) {
+			t.Fatalf("Walk failed:\nwant %v\ngot  %v", test.want, paths)
+		}
+	}
+}
+
+func TestWalkActions(t *testing.T) {
+	node := &WalkNode{Name: "root", Items: []int{1, 2}, Next: &WalkNode{Name: "next"}}
+	paths := []string{}
+	WalkOptions{FollowPointers: true}.Walk(node, PreOrder(
+		func(path string, field *reflect.StructField, v reflect.Value) WalkAction {
+			paths = append(paths, path)
+			switch path {
+			case "Items":
+				return SkipChildren
+			case "Next.Items":
+				return Stop
+			}
+			return Continue
+		}))
+	want := []string{"", "Name", "Items", "Attrs", "Next", "Next", "Next.Name", "Next.Items"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("Walk failed:\nwant %v\ngot  %v", want, paths)
+	}
+	paths = paths[:0]
+	Walk([]int{1, 2}, PostOrder(
+		func(path string, field *reflect.StructField, v reflect.Value) WalkAction {
+			paths = append(paths, path)
+			return Continue
+		}))
+	if !reflect.DeepEqual(paths, []string{"[0]", "[1]", ""}) {
+		t.Fatalf("Walk failed: %v", paths)
+	}
+}
+
+func TestWalkAllowUnexported(t *testing.T) {
+	node := WalkNode{Name: "root"}
+	set := func(opts WalkOptions) (settable bool) {
+		opts.Walk(&node, PreOrder(
+			func(path string, field *reflect.StructField, v reflect.Value) WalkAction {
+				if path == "hidden" {
+					settable = v.CanSet()
+				}
+				return Continue
+			}))
+		return
+	}
+	if set(WalkOptions{}) {
+		t.Fatal("Walk failed, unexported field settable by default")
+	}
+	if !set(WalkOptions{AllowUnexported: true}) {
+		t.Fatal("Walk(AllowUnexported) failed")
+	}
+}
+
+// countingVisitor is a Visitor counting calls.
+type countingVisitor struct {
+	enters, leaves *int
+}
+
+func (cv *countingVisitor) Enter(string, *reflect.StructField, reflect.Value) WalkAction {
+	*cv.enters++
+	return Continue
+}
+
+func (cv *countingVisitor) Leave(string, *reflect.StructField, reflect.Value) WalkAction {
+	*cv.leaves++
+	return Continue
+}
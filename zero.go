@@ -0,0 +1,289 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import "reflect"
+
+// zeroerType is the type of interface{ IsZero() bool }.
+var zeroerType = reflect.TypeOf((*interface{ IsZero() bool })(nil)).Elem()
+
+// ZeroOptions define what IsZeroDeep considers to be zero.
+type ZeroOptions struct {
+	// NilOnly, if true, treats only nil slices and maps as zero instead of
+	// empty ones.
+	NilOnly bool
+	// Elements, if true, treats slices and maps whose elements are all zero
+	// as zero. Arrays are always zero if all elements are zero.
+	Elements bool
+	// Pointers, if true, treats non-nil pointers as zero if values they
+	// point to are zero.
+	Pointers bool
+	// ExportedOnly, if true, ignores unexported struct fields.
+	ExportedOnly bool
+	// IgnoreZeroer, if true, ignores IsZero() bool methods of values and
+	// inspects them by kind instead.
+	IgnoreZeroer bool
+	// AllowUnexported, if true, makes unexported struct fields accessible
+	// as if exported, using package unsafe, so that their IsZero() bool
+	// methods are called and PruneZero prunes them. See
+	// StructOptions.AllowUnexported.
+	AllowUnexported bool
+}
+
+// IsZeroDeep returns true if v is deeply zero using zero ZeroOptions.
+// See ZeroOptions.IsZeroDeepValue.
+func IsZeroDeep(v interface{}) bool {
+	return ZeroOptions{}.IsZeroDeepValue(reflect.ValueOf(v))
+}
+
+// IsZeroDeep returns true if v is deeply zero using options o.
+// See IsZeroDeepValue.
+func (o ZeroOptions) IsZeroDeep(v interface{}) bool {
+	return o.IsZeroDeepValue(reflect.ValueOf(v))
+}
+
+// IsZeroDeepValue returns true if v is deeply zero using zero ZeroOptions.
+func IsZeroDeepValue(v reflect.Value) bool {
+	return ZeroOptions{}.IsZeroDeepValue(v)
+}
+
+// IsZeroDeepValue returns true if v is deeply zero. An invalid value is
+// zero. Values implementing IsZero() bool, such as time.Time, are zero if
+// their method says so. Empty strings, slices and maps are zero, as are
+// structs whose fields and arrays whose elements are all deeply zero. Nil
+// pointers are zero and interfaces are zero if nil or if their values are.
+// Other values are zero if reflect says so. Options o modify these rules as
+// documented.
+func (o ZeroOptions) IsZeroDeepValue(v reflect.Value) bool {
+	return o.isZero(v, make(map[visit]bool))
+}
+
+// isZero is the implementation of IsZeroDeepValue. Visited holds pointers,
+// maps and slices being inspected; a reference cycle is considered non-zero.
+func (o ZeroOptions) isZero(v reflect.Value, visited map[visit]bool) bool {
+	if !v.IsValid() {
+		return true
+	}
+	if !o.IgnoreZeroer && v.Type().Implements(zeroerType) && v.CanInterface() {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return true
+		}
+		return v.Interface().(interface{ IsZero() bool }).IsZero()
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return true
+		}
+		if v.Kind() == reflect.Ptr && !o.Pointers {
+			return false
+		}
+		if v.Kind() == reflect.Ptr {
+			key := visitOf(v)
+			if visited[key] {
+				return false
+			}
+			visited[key] = true
+			defer delete(visited, key)
+		}
+		return o.isZero(v.Elem(), visited)
+	case reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			return true
+		}
+		if v.Len() == 0 {
+			return !o.NilOnly
+		}
+		if !o.Elements {
+			return false
+		}
+		key := visitOf(v)
+		if visited[key] {
+			return false
+		}
+		visited[key] = true
+		defer delete(visited, key)
+		if v.Kind() == reflect.Map {
+			iter := v.MapRange()
+			for iter.Next() {
+				if !o.isZero(iter.Value(), visited) {
+					return false
+				}
+			}
+			return true
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !o.isZero(v.Index(i), visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if o.ExportedOnly && v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			field := v.Field(i)
+			if o.AllowUnexported {
+				field = exposed(field)
+			}
+			if !o.isZero(field, visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.String:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// Zero resets the value v points to to its zero value in place. Non-nil
+// exported pointer fields of structs, including nested ones, are kept and
+// the values they point to are reset instead. V must be a non-nil pointer.
+func Zero(v interface{}) error {
+	pv := reflect.ValueOf(v)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() {
+		return ErrInvalidParam
+	}
+	zeroValue(pv.Elem(), make(map[visit]bool))
+	return nil
+}
+
+// zeroValue resets settable v in place. Visited holds reset pointers.
+func zeroValue(v reflect.Value, visited map[visit]bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		key := visitOf(v)
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+		zeroValue(v.Elem(), visited)
+		return
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			zeroValue(v.Index(i), visited)
+		}
+		return
+	case reflect.Struct:
+		ptrs := make(map[int]reflect.Value)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if field.Kind() == reflect.Ptr && !field.IsNil() && field.CanSet() {
+				ptrs[i] = reflect.ValueOf(field.Interface())
+			}
+		}
+		v.Set(reflect.Zero(v.Type()))
+		for i, ptr := range ptrs {
+			v.Field(i).Set(ptr)
+			zeroValue(v.Field(i), visited)
+		}
+		return
+	}
+	v.Set(reflect.Zero(v.Type()))
+}
+
+// PruneZero removes entries with deeply zero values from maps and sets
+// pointers to deeply zero values to nil, recursively, in the value v points
+// to, using zero ZeroOptions. See ZeroOptions.PruneZero.
+func PruneZero(v interface{}) error {
+	return ZeroOptions{}.PruneZero(v)
+}
+
+// PruneZero removes entries with deeply zero values from maps and sets
+// pointers to deeply zero values to nil, recursively, in the value v points
+// to, using options o to determine zero values. Settable struct fields,
+// slice and array elements, map values and values in interfaces are pruned.
+// Unexported struct fields are pruned only if o.AllowUnexported is true.
+// V must be a non-nil pointer.
+func (o ZeroOptions) PruneZero(v interface{}) error {
+	pv := reflect.ValueOf(v)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() {
+		return ErrInvalidParam
+	}
+	o.prune(pv.Elem(), make(map[visit]bool))
+	return nil
+}
+
+// prune is the implementation of PruneZero. Visited holds pruned pointers,
+// maps and slices.
+func (o ZeroOptions) prune(v reflect.Value, visited map[visit]bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		key := visitOf(v)
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+		o.prune(v.Elem(), visited)
+		if v.CanSet() && o.isZero(v.Elem(), make(map[visit]bool)) {
+			v.Set(reflect.Zero(v.Type()))
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		elem := v.Elem()
+		if elem.Kind() == reflect.Map || elem.Kind() == reflect.Ptr {
+			o.prune(elem, visited)
+			return
+		}
+		if v.CanSet() {
+			copied := reflect.New(elem.Type()).Elem()
+			copied.Set(elem)
+			o.prune(copied, visited)
+			v.Set(copied)
+		}
+	case reflect.Array, reflect.Slice:
+		if v.Kind() == reflect.Slice && v.Len() > 0 {
+			key := visitOf(v)
+			if visited[key] {
+				return
+			}
+			visited[key] = true
+		}
+		for i := 0; i < v.Len(); i++ {
+			o.prune(v.Index(i), visited)
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		ref := visitOf(v)
+		if visited[ref] {
+			return
+		}
+		visited[ref] = true
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			o.prune(elem, visited)
+			if o.isZero(elem, make(map[visit]bool)) {
+				v.SetMapIndex(key, reflect.Value{})
+				continue
+			}
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if o.AllowUnexported {
+				field = exposed(field)
+			}
+			if field.CanSet() {
+				o.prune(field, visited)
+			}
+		}
+	}
+}
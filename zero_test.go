@@ -0,0 +1,198 @@
+// Copyright 2020 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package reflectex
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIsZeroDeep(t *testing.T) {
+
+	type Inner struct {
+		Name string
+		Tags []string
+	}
+
+	type Test struct {
+		Inner  Inner
+		Map    map[string]int
+		Time   time.Time
+		Ptr    *Inner
+		hidden int
+	}
+
+	zero := 0
+	tests := []struct {
+		opts ZeroOptions
+		in   interface{}
+		want bool
+	}{
+		{ZeroOptions{}, nil, true},
+		{ZeroOptions{}, 0, true},
+		{ZeroOptions{}, "", true},
+		{ZeroOptions{}, []int{}, true},
+		{ZeroOptions{NilOnly: true}, []int{}, false},
+		{ZeroOptions{}, []int{0, 0}, false},
+		{ZeroOptions{Elements: true}, []int{0, 0}, true},
+		{ZeroOptions{Elements: true}, map[string]int{"a": 0}, true},
+		{ZeroOptions{}, [2]int{}, true},
+		{ZeroOptions{}, &zero, false},
+		{ZeroOptions{Pointers: true}, &zero, true},
+		{ZeroOptions{}, Test{Map: map[string]int{}, Inner: Inner{Tags: []string{}}}, true},
+		{ZeroOptions{}, Test{Time: time.Now()}, false},
+		{ZeroOptions{}, Test{Ptr: &Inner{}}, false},
+		{ZeroOptions{Pointers: true}, Test{Ptr: &Inner{}}, true},
+		{ZeroOptions{}, Test{hidden: 1}, false},
+		{ZeroOptions{ExportedOnly: true}, Test{hidden: 1}, true},
+		{ZeroOptions{}, time.Time{}, true},
+	}
+	for i, test := range tests {
+		if got := test.opts.IsZeroDeep(test.in); got != test.want {
+			t.Fatalf("IsZeroDeep failed at %d: want %t, got %t", i, test.want, got)
+		}
+	}
+	if !IsZeroDeep(Test{}) || IsZeroDeep(1) {
+		t.Fatal("IsZeroDeep failed")
+	}
+}
+
+func TestIsZeroDeepCycle(t *testing.T) {
+	type Node struct {
+		Next *Node
+	}
+	node := &Node{}
+	node.Next = node
+	if (ZeroOptions{Pointers: true}).IsZeroDeep(node) {
+		t.Fatal("IsZeroDeep failed")
+	}
+	m := map[string]interface{}{}
+	m["self"] = m
+	if (ZeroOptions{Elements: true}).IsZeroDeep(m) {
+		t.Fatal("IsZeroDeep failed on map cycle")
+	}
+	s := []interface{}{nil}
+	s[0] = s
+	if (ZeroOptions{Elements: true}).IsZeroDeep(s) {
+		t.Fatal("IsZeroDeep failed on slice cycle")
+	}
+}
+
+func TestZero(t *testing.T) {
+
+	type Inner struct {
+		Name string
+	}
+
+	type Test struct {
+		Name   string
+		Inner  *Inner
+		Nums   []int
+		Arr    [2]Inner
+		hidden int
+	}
+
+	inner := &Inner{"inner"}
+	test := &Test{"test", inner, []int{1}, [2]Inner{{"a"}, {"b"}}, 42}
+	if err := Zero(test); err != nil {
+		t.Fatal(err)
+	}
+	if test.Inner != inner || inner.Name != "" {
+		t.Fatal("Zero failed, bad nested pointer")
+	}
+	test.Inner = nil
+	if !reflect.DeepEqual(test, &Test{}) {
+		t.Fatalf("Zero failed: %#v", test)
+	}
+	if err := Zero(Test{}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("Zero failed, expected invalid param error")
+	}
+}
+
+func TestPruneZero(t *testing.T) {
+
+	type Inner struct {
+		Name string
+	}
+
+	type Test struct {
+		Map    map[string]interface{}
+		Ptr    *Inner
+		Keep   *Inner
+		Nested map[string]map[string]int
+		Slice  []*Inner
+	}
+
+	test := &Test{
+		Map: map[string]interface{}{
+			"empty": "",
+			"nil":   nil,
+			"keep":  1,
+			"inner": map[string]int{"zero": 0, "one": 1},
+		},
+		Ptr:    &Inner{},
+		Keep:   &Inner{"keep"},
+		Nested: map[string]map[string]int{"a": {"zero": 0}, "b": {"one": 1}},
+		Slice:  []*Inner{{}, {"keep"}},
+	}
+	if err := PruneZero(test); err != nil {
+		t.Fatal(err)
+	}
+	want := &Test{
+		Map: map[string]interface{}{
+			"keep":  1,
+			"inner": map[string]int{"one": 1},
+		},
+		Keep:   &Inner{"keep"},
+		Nested: map[string]map[string]int{"b": {"one": 1}},
+		Slice:  []*Inner{nil, {"keep"}},
+	}
+	if !reflect.DeepEqual(test, want) {
+		t.Fatalf("PruneZero failed: %#v", test)
+	}
+	m := map[string]interface{}{"empty": ""}
+	m["self"] = m
+	if err := (ZeroOptions{Elements: true}).PruneZero(&m); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["empty"]; ok || len(m) != 1 {
+		t.Fatalf("PruneZero failed on map cycle: %v", len(m))
+	}
+	s := []interface{}{map[string]interface{}{"empty": ""}}
+	s[0].(map[string]interface{})["self"] = s
+	if err := PruneZero(&s); err != nil {
+		t.Fatal(err)
+	}
+	if len(s[0].(map[string]interface{})) != 1 {
+		t.Fatal("PruneZero failed on slice cycle")
+	}
+	if err := PruneZero(nil); !errors.Is(err, ErrInvalidParam) {
+		t.Fatal("PruneZero failed, expected invalid param error")
+	}
+}
+
+func TestZeroOptionsAllowUnexported(t *testing.T) {
+
+	type Test struct {
+		Exported map[string]int
+		hidden   map[string]int
+	}
+
+	test := &Test{map[string]int{"zero": 0}, map[string]int{"zero": 0}}
+	if err := PruneZero(test); err != nil {
+		t.Fatal(err)
+	}
+	if len(test.Exported) != 0 || len(test.hidden) != 1 {
+		t.Fatal("PruneZero failed, pruned unexported fields by default")
+	}
+	if err := (ZeroOptions{AllowUnexported: true}).PruneZero(test); err != nil {
+		t.Fatal(err)
+	}
+	if len(test.hidden) != 0 {
+		t.Fatal("PruneZero(AllowUnexported) failed")
+	}
+}